@@ -0,0 +1,213 @@
+// Command envycrypt manages encrypted values in .env files read by envy:
+//
+//	envycrypt keygen --out .envy.key
+//	envycrypt encrypt --file .env.production --key-file .envy.key DB_PASSWORD API_TOKEN
+//	envycrypt decrypt --file .env.production --key-file .envy.key DB_PASSWORD
+//	envycrypt rotate --file .env.production --key-file .envy.key --new-key-file .envy.key.new
+//
+// The key is read from --key-file, or the ENVY_KEY variable.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moeghifar/libgo/pkg/climd"
+	"github.com/moeghifar/libgo/pkg/envy"
+)
+
+func main() {
+	climd.Execute(app(os.Stdout))
+}
+
+var (
+	fileFlag    = climd.Flag{Name: "file", Short: "f", Usage: ".env file to edit", Required: true}
+	keyFileFlag = climd.Flag{Name: "key-file", Short: "k", Usage: "file holding the key, ENVY_KEY is used otherwise"}
+)
+
+func app(out io.Writer) climd.AppConfig {
+	return climd.AppConfig{
+		Name:        "envycrypt",
+		Version:     "1.0.0",
+		Description: "Encrypt values of .env files for envy",
+		Commands: []climd.Command{
+			{
+				Name:  "keygen",
+				Short: "Generate a key",
+				Long:  "Prints a new random key, or writes it to --out with 0600 permissions. An existing key file is only replaced with --force, values encrypted with it can't be read anymore.",
+				Flags: []climd.Flag{
+					{Name: "out", Short: "o", Usage: "file to write the key to"},
+					{Name: "force", Usage: "replace an existing key file"},
+				},
+				Run: func(ctx context.Context, args []string, flags map[string]string) error {
+					key, err := envy.GenerateKey()
+					if err != nil {
+						return err
+					}
+					if path := flag(flags, "out", "o"); path != "" {
+						_, force := flags["force"]
+						return writeKey(path, key, force)
+					}
+					_, err = fmt.Fprintln(out, key)
+					return err
+				},
+			},
+			{
+				Name:  "encrypt",
+				Short: "Encrypt the values of the given keys in place",
+				Flags: []climd.Flag{fileFlag, keyFileFlag},
+				Run: func(ctx context.Context, args []string, flags map[string]string) error {
+					if len(args) == 0 {
+						return errors.New("no keys to encrypt given")
+					}
+					key, err := readKey(flag(flags, "key-file", "k"))
+					if err != nil {
+						return err
+					}
+					return editFile(flag(flags, "file", "f"), func(data []byte) ([]byte, error) {
+						return envy.EncryptEnv(data, key, args...)
+					})
+				},
+			},
+			{
+				Name:  "decrypt",
+				Short: "Print the plaintext value of a key",
+				Flags: []climd.Flag{fileFlag, keyFileFlag},
+				Run: func(ctx context.Context, args []string, flags map[string]string) error {
+					if len(args) != 1 {
+						return errors.New("expected exactly one key to decrypt")
+					}
+					key, err := readKey(flag(flags, "key-file", "k"))
+					if err != nil {
+						return err
+					}
+					src, err := envy.DotenvSource(flag(flags, "file", "f"))
+					if err != nil {
+						return err
+					}
+					value, ok := src.Lookup(args[0])
+					if !ok {
+						return fmt.Errorf("%s is not set in the file", args[0])
+					}
+					plaintext, err := envy.Decrypt(key, args[0], value)
+					if err != nil {
+						return fmt.Errorf("%s: %w", args[0], err)
+					}
+					_, err = fmt.Fprintln(out, plaintext)
+					return err
+				},
+			},
+			{
+				Name:  "rotate",
+				Short: "Re-encrypt every value with a new key",
+				Flags: []climd.Flag{fileFlag, keyFileFlag, {Name: "new-key-file", Usage: "file holding the new key", Required: true}},
+				Run: func(ctx context.Context, args []string, flags map[string]string) error {
+					oldKey, err := readKey(flag(flags, "key-file", "k"))
+					if err != nil {
+						return err
+					}
+					newKey, err := readKey(flags["new-key-file"])
+					if err != nil {
+						return err
+					}
+					return editFile(flag(flags, "file", "f"), func(data []byte) ([]byte, error) {
+						return envy.RotateEnv(data, oldKey, newKey)
+					})
+				},
+			},
+		},
+	}
+}
+
+// flag returns the value of a flag given by its long or short name.
+func flag(flags map[string]string, name, short string) string {
+	if v, ok := flags[name]; ok {
+		return v
+	}
+	return flags[short]
+}
+
+// readKey reads the key from path, or ENVY_KEY when path is empty.
+func readKey(path string) (string, error) {
+	if path == "" {
+		if key := os.Getenv("ENVY_KEY"); key != "" {
+			return key, nil
+		}
+		return "", errors.New("no key given, use --key-file or set ENVY_KEY")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeKey writes key to a new file at path, readable by its owner only.
+// An existing file is an error unless force is set, it is then replaced.
+func writeKey(path, key string, force bool) error {
+	if force {
+		// Removing the file first creates the new one with 0600 whatever
+		// the mode of the old one
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to replace it", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// editFile rewrites path with edit, keeping its permissions. The new
+// content is written to a temporary file next to path, then renamed over
+// it, so a failed write leaves the original untouched.
+func editFile(path string, edit func([]byte) ([]byte, error)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err = edit(data)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Fails once renamed
+	if err := f.Chmod(info.Mode().Perm()); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moeghifar/libgo/pkg/climd"
+)
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := climd.Run(app(&out), args)
+	return strings.TrimSpace(out.String()), err
+}
+
+func TestEnvycrypt(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env.production")
+	oldKey, newKey := filepath.Join(dir, "old.key"), filepath.Join(dir, "new.key")
+	if err := os.WriteFile(env, []byte("DB_HOST=db\nDB_PASSWORD=hunter2\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{oldKey, newKey} {
+		if _, err := run(t, "keygen", "--out", key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, _ := os.Stat(oldKey); info.Mode().Perm() != 0600 {
+		t.Errorf("expected key file mode 0600, got %v", info.Mode())
+	}
+
+	// Existing keys are only replaced with --force, always with 0600
+	before, _ := os.ReadFile(oldKey)
+	if _, err := run(t, "keygen", "--out", oldKey); err == nil || !strings.Contains(err.Error(), "use --force") {
+		t.Errorf("expected an error for an existing key file, got %v", err)
+	}
+	if after, _ := os.ReadFile(oldKey); string(after) != string(before) {
+		t.Error("expected the existing key to be kept")
+	}
+	forced := filepath.Join(dir, "forced.key")
+	if err := os.WriteFile(forced, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, "keygen", "--out", forced, "--force"); err != nil {
+		t.Fatalf("expected no error with --force, got %v", err)
+	}
+	if data, _ := os.ReadFile(forced); string(data) == "old\n" {
+		t.Error("expected --force to replace the key")
+	}
+	if info, _ := os.Stat(forced); info.Mode().Perm() != 0600 {
+		t.Errorf("expected key file mode 0600, got %v", info.Mode())
+	}
+
+	if _, err := run(t, "encrypt", "-f", env, "-k", oldKey, "DB_PASSWORD"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(env)
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), "DB_HOST=db\n") {
+		t.Errorf("expected only DB_PASSWORD to be encrypted, got:\n%s", data)
+	}
+	if info, _ := os.Stat(env); info.Mode().Perm() != 0640 {
+		t.Errorf("expected file mode 0640, got %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("expected no temporary file to be left, got %v", entries)
+	}
+
+	if _, err := run(t, "rotate", "--file", env, "--key-file", oldKey, "--new-key-file", newKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, "decrypt", "--file", env, "--key-file", oldKey, "DB_PASSWORD"); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected an error without the value for the old key, got %v", err)
+	}
+
+	t.Setenv("ENVY_KEY", "")
+	if _, err := run(t, "decrypt", "--file", env, "DB_PASSWORD"); err == nil {
+		t.Error("expected an error without a key")
+	}
+	key, _ := os.ReadFile(newKey)
+	t.Setenv("ENVY_KEY", strings.TrimSpace(string(key)))
+	if out, err := run(t, "decrypt", "--file", env, "DB_PASSWORD"); err != nil || out != "hunter2" {
+		t.Errorf("expected hunter2, got %q (error: %v)", out, err)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const runtimePath = "github.com/moeghifar/libgo/pkg/envy/envyrt"
+
+// unsupportedTags are envy tags the generated code can't honor, rejected
+// rather than silently ignored.
+var unsupportedTags = []string{"min", "max", "oneof", "pattern", "validate", "file", "secret", "deprecated"}
+
+// generate returns the source of the loader of typeName, declared in the
+// package in dir. The output file, if it already exists, is left out of
+// type checking.
+func generate(dir, typeName, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct type", typeName)
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]bool{runtimePath: true, "os": true}}
+	if err := g.walk(st, "", "", "cfg"); err != nil {
+		return nil, err
+	}
+
+	// Standard library imports first, as goimports groups them
+	var std, others []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(others)
+
+	var buf bytes.Buffer
+	err = fileTemplate.Execute(&buf, map[string]any{
+		"Package": pkg.Name(),
+		"Type":    typeName,
+		"Imports": [][]string{std, others},
+		"Fields":  g.fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+// loadPackage type checks the package in dir, skipping the file named
+// skip. Errors are tolerated as long as the config type itself resolves.
+func loadPackage(dir, skip string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == skip {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil), Error: func(error) {}}
+	pkg, _ := conf.Check(bp.Name, fset, files, nil)
+	return pkg, nil
+}
+
+// field is a config field with an env tag, as used by the template.
+type field struct {
+	Key      string
+	Path     string
+	Default  string
+	Required bool
+	Target   string // Go expression of the field, e.g. cfg.Database.DSN
+	Parse    string // Go expression parsing the value v
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]bool // Import paths used by the generated code
+	fields  []field
+}
+
+// walk collects the fields of st the way envy's parser visits them:
+// nested structs are descended into with their envPrefix, fields without
+// an env tag are skipped.
+func (g *generator) walk(st *types.Struct, prefix, path, target string) error {
+	for i := 0; i < st.NumFields(); i++ {
+		f, tag := st.Field(i), reflect.StructTag(st.Tag(i))
+		fieldPath := f.Name()
+		if path != "" {
+			fieldPath = path + "." + f.Name()
+		}
+
+		if _, ok := f.Type().(*types.Pointer); ok {
+			if tag.Get("env") != "" || tag.Get("envPrefix") != "" || isNestedStruct(f.Type().Underlying().(*types.Pointer).Elem()) {
+				return fmt.Errorf("field %s: pointer fields are not supported by envygen", fieldPath)
+			}
+			continue
+		}
+		if tag.Get("env") == "" && isNestedStruct(f.Type()) {
+			nested := f.Type().Underlying().(*types.Struct)
+			if err := g.walk(nested, prefix+tag.Get("envPrefix"), fieldPath, target+"."+f.Name()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := tag.Get("env")
+		if key == "" {
+			continue
+		}
+		for _, name := range unsupportedTags {
+			if _, ok := tag.Lookup(name); ok {
+				return fmt.Errorf("field %s: the %s tag is not supported by envygen", fieldPath, name)
+			}
+		}
+
+		parse, err := g.fieldParser(f.Type(), tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fieldPath, err)
+		}
+		g.fields = append(g.fields, field{
+			Key:      prefix + key,
+			Path:     fieldPath,
+			Default:  tag.Get("default"),
+			Required: tag.Get("required") == "true",
+			Target:   target + "." + f.Name(),
+			Parse:    parse,
+		})
+	}
+	return nil
+}
+
+// fieldParser returns the expression parsing the value v into type t,
+// splitting slices and maps with the sep and kvsep tags.
+func (g *generator) fieldParser(t types.Type, tag reflect.StructTag) (string, error) {
+	if !isValueType(t) {
+		sep, kvSep := tag.Get("sep"), tag.Get("kvsep")
+		if sep == "" {
+			sep = ","
+		}
+		if kvSep == "" {
+			kvSep = ":"
+		}
+
+		switch u := t.Underlying().(type) {
+		case *types.Slice:
+			elem, err := g.valueParser(u.Elem(), tag)
+			if err != nil {
+				return "", fmt.Errorf("slice element: %w", err)
+			}
+			return fmt.Sprintf("envyrt.Slice(v, %s, %s)", strconv.Quote(sep), elem), nil
+		case *types.Map:
+			key, err := g.valueParser(u.Key(), tag)
+			if err != nil {
+				return "", fmt.Errorf("map key: %w", err)
+			}
+			elem, err := g.valueParser(u.Elem(), tag)
+			if err != nil {
+				return "", fmt.Errorf("map value: %w", err)
+			}
+			return fmt.Sprintf("envyrt.Map(v, %s, %s, %s, %s)", strconv.Quote(sep), strconv.Quote(kvSep), key, elem), nil
+		}
+	}
+
+	parse, err := g.valueParser(t, tag)
+	if err != nil {
+		return "", err
+	}
+	return parse + "(v)", nil
+}
+
+// valueParser returns the envyrt function parsing a single value of type
+// t, checking types in the same order as envy's setField.
+func (g *generator) valueParser(t types.Type, tag reflect.StructTag) (string, error) {
+	switch {
+	case isNamed(t, "time", "Duration"):
+		return "envyrt.Duration", nil
+	case isNamed(t, "time", "Time"):
+		return fmt.Sprintf("envyrt.Time(%s)", strconv.Quote(tag.Get("layout"))), nil
+	case isTextUnmarshaler(t):
+		return "envyrt.Text[" + g.typeString(t) + "]", nil
+	}
+
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		info := b.Info()
+		switch {
+		case info&types.IsString != 0:
+			return "envyrt.String[" + g.typeString(t) + "]", nil
+		case info&types.IsBoolean != 0:
+			return "envyrt.Bool[" + g.typeString(t) + "]", nil
+		case info&types.IsUnsigned != 0:
+			return "envyrt.Uint[" + g.typeString(t) + "]", nil
+		case info&types.IsInteger != 0:
+			return "envyrt.Int[" + g.typeString(t) + "]", nil
+		case info&types.IsFloat != 0:
+			return "envyrt.Float[" + g.typeString(t) + "]", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// typeString writes t as seen from the generated file, recording the
+// imports it needs.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = true
+		return p.Name()
+	})
+}
+
+func isNamed(t types.Type, pkg, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == pkg && n.Obj().Name() == name
+}
+
+// isTextUnmarshaler reports whether *t has an UnmarshalText method.
+func isTextUnmarshaler(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "UnmarshalText")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// isValueType mirrors envy's isValueType, without registered decoders which
+// only exist at run time.
+func isValueType(t types.Type) bool {
+	return isNamed(t, "time", "Time") || isTextUnmarshaler(t)
+}
+
+func isNestedStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok && !isValueType(t)
+}
+
+var fileTemplate = template.Must(template.New("").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`// Code generated by envygen -type {{.Type}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range $i, $group := .Imports}}{{if $i}}
+{{end}}{{range $group}}
+	{{quote .}}
+{{- end}}{{end}}
+)
+
+// Load{{.Type}} loads a {{.Type}} from the process environment, like
+// envy.Parse without reflection. Unlike envy.Load, .env files are not read.
+func Load{{.Type}}() ({{.Type}}, error) {
+	return Load{{.Type}}From(os.LookupEnv)
+}
+
+// Load{{.Type}}From loads a {{.Type}} reading variables with lookup.
+func Load{{.Type}}From(lookup func(string) (string, bool)) ({{.Type}}, error) {
+	var cfg {{.Type}}
+	l := envyrt.NewLoader(lookup, map[string]string{
+{{- range .Fields}}{{if .Default}}
+		{{quote .Key}}: {{quote .Default}},
+{{- end}}{{end}}
+	})
+{{range .Fields}}
+	if v, ok := l.Value({{quote .Key}}, {{quote .Path}}, {{quote .Default}}, {{.Required}}); ok {
+		if x, err := {{.Parse}}; err != nil {
+			l.Fail({{quote .Key}}, {{quote .Path}}, v, err)
+		} else {
+			{{.Target}} = x
+		}
+	}
+{{end}}
+	return cfg, l.Err()
+}
+`))
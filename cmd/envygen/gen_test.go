@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerate_Golden checks the committed fixture output is up to date, run
+// go generate ./cmd/envygen/... after changing the generator.
+func TestGenerate_Golden(t *testing.T) {
+	dir := filepath.Join("internal", "fixture")
+	got, err := generate(dir, "Config", "config_envy.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "config_envy.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("generated code differs from %s, run go generate", filepath.Join(dir, "config_envy.go"))
+	}
+	if strings.Contains(string(got), `"reflect"`) {
+		t.Error("generated code imports reflect")
+	}
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"validation tag", "type Config struct {\n\tPort int `env:\"PORT\" min:\"1\"`\n}", "field Port: the min tag is not supported"},
+		{"pointer", "type Config struct {\n\tPort *int `env:\"PORT\"`\n}", "field Port: pointer fields are not supported"},
+		{"nested pointer", "type Config struct {\n\tDB *struct{ DSN string `env:\"DSN\"` }\n}", "field DB: pointer fields are not supported"},
+		{"type", "type Config struct {\n\tC complex128 `env:\"C\"`\n}", "field C: unsupported type complex128"},
+		{"struct value", "type Config struct {\n\tS struct{ A string } `env:\"S\"`\n}", "field S: unsupported type struct{A string}"},
+		{"slice of slices", "type Config struct {\n\tS [][]int `env:\"S\"`\n}", "field S: slice element: unsupported type []int"},
+		{"not a struct", "type Config int", "Config is not a struct type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte("package config\n\n"+tt.src+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := generate(dir, "Config", "config_envy.go")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
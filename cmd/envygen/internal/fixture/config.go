@@ -0,0 +1,43 @@
+// Package fixture holds a config struct loaded both by envy and by the
+// code envygen generates for it, to check they agree.
+package fixture
+
+import (
+	"net"
+	"time"
+)
+
+//go:generate go run github.com/moeghifar/libgo/cmd/envygen -type Config
+
+type Level string
+
+type Config struct {
+	Port    int              `env:"PORT" default:"8080"`
+	Host    string           `env:"HOST" required:"true"`
+	Debug   bool             `env:"DEBUG"`
+	Level   Level            `env:"LEVEL" default:"info"`
+	Ratio   float32          `env:"RATIO" default:"0.5"`
+	Workers uint8            `env:"WORKERS" default:"4"`
+	Timeout time.Duration    `env:"TIMEOUT" default:"30s"`
+	Cutoff  time.Time        `env:"CUTOFF" layout:"2006-01-02"`
+	Started time.Time        `env:"STARTED"`
+	IP      net.IP           `env:"IP"`
+	URL     string           `env:"URL" default:"http://${HOST}:${PORT}"`
+	Hosts   []string         `env:"HOSTS" default:"localhost"`
+	Backoff []time.Duration  `env:"BACKOFF" sep:";"`
+	Limits  map[string]int   `env:"LIMITS"`
+	Labels  map[Level]string `env:"LABELS" sep:";" kvsep:"="`
+	Ignored string
+
+	Database struct {
+		DSN  string `env:"DSN" required:"true" default:"postgres://${HOST}/app"`
+		Pool int    `env:"POOL"`
+	} `envPrefix:"DB_"`
+
+	Cache CacheConfig `envPrefix:"CACHE_"`
+}
+
+type CacheConfig struct {
+	Size int           `env:"SIZE" default:"128"`
+	TTL  time.Duration `env:"TTL"`
+}
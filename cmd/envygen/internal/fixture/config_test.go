@@ -0,0 +1,93 @@
+package fixture
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moeghifar/libgo/pkg/envy"
+)
+
+// TestGeneratedMatchesEnvy loads the same variables with envy and with the
+// generated code, both must return the same config and errors.
+func TestGeneratedMatchesEnvy(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"defaults", map[string]string{"HOST": "example.com"}},
+		{"all set", map[string]string{
+			"PORT": "9090", "HOST": "example.com", "DEBUG": "true", "LEVEL": "debug",
+			"RATIO": "0.25", "WORKERS": "16", "TIMEOUT": "1m30s", "CUTOFF": "2024-02-29",
+			"STARTED": "2024-01-02T15:04:05Z", "IP": "10.0.0.1", "URL": "https://${HOST}",
+			"HOSTS": "a, b,,c", "BACKOFF": "1s;2s;4s", "LIMITS": "acme:100, globex:250",
+			"LABELS": "debug=verbose;info=normal", "DB_DSN": "postgres://db/app", "DB_POOL": "10",
+			"CACHE_SIZE": "256", "CACHE_TTL": "5m",
+		}},
+		{"required missing", map[string]string{"DB_DSN": ""}},
+		{"empty values", map[string]string{"HOST": "h", "PORT": "", "HOSTS": "", "LIMITS": ","}},
+		{"parse errors", map[string]string{
+			"HOST": "h", "PORT": "http", "DEBUG": "maybe", "RATIO": "half", "WORKERS": "-1",
+			"TIMEOUT": "soon", "CUTOFF": "29/02/2024", "IP": "10.0.0", "HOSTS": "a",
+			"BACKOFF": "1s;x", "LIMITS": "acme", "CACHE_SIZE": "1e3",
+		}},
+		{"interpolation errors", map[string]string{"HOST": "${MISSING:?set it}", "URL": "${URL}"}},
+		{"out of range", map[string]string{"HOST": "h", "WORKERS": "300", "DB_POOL": "99999999999999999999"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantErr := envy.Parse[Config](envy.WithSources(envy.MapSource(tt.env)))
+			got, gotErr := LoadConfigFrom(func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected the config of envy %+v, got %+v", want, got)
+			}
+			if errString(gotErr) != errString(wantErr) {
+				t.Errorf("expected the error of envy %v, got %v", wantErr, gotErr)
+			}
+			if tt.name == "out of range" && (!errors.Is(gotErr, envy.ErrParse) || !strings.Contains(errString(gotErr), "WORKERS")) {
+				t.Errorf("expected a parse error for WORKERS, got %v", gotErr)
+			}
+			for _, sentinel := range []error{envy.ErrRequired, envy.ErrParse} {
+				if errors.Is(gotErr, sentinel) != errors.Is(wantErr, sentinel) {
+					t.Errorf("expected errors.Is(%v) to match envy for %v", sentinel, gotErr)
+				}
+			}
+		})
+	}
+}
+
+// TestGeneratedRefusesSecrets checks that values envy would resolve or
+// decrypt fail instead of being loaded as is.
+func TestGeneratedRefusesSecrets(t *testing.T) {
+	env := map[string]string{"HOST": "ref+file:///run/secrets/host", "DB_DSN": "ENC[AES256_GCM,c2VjcmV0]"}
+	_, err := LoadConfigFrom(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+
+	// URL references HOST in its default
+	var errs *envy.Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 3 || !errors.Is(err, envy.ErrUnsupported) {
+		t.Fatalf("expected 3 unsupported errors, got %v", err)
+	}
+	for _, fe := range errs.Errs {
+		for _, secret := range env {
+			if strings.Contains(fe.Value, secret) || strings.Contains(fe.Error(), secret) {
+				t.Errorf("expected the secret values to be left out of %s, got %v (%q)", fe.Key, fe, fe.Value)
+			}
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,58 @@
+// Command envygen generates a function loading a config struct without
+// reflection, following the struct tags of pkg/envy. It is meant for small
+// static binaries, usually with the libgo_envy_slim build tag:
+//
+//	//go:generate go run github.com/moeghifar/libgo/cmd/envygen -type Config
+//
+// For a type Config it writes config_envy.go, next to the type, with
+//
+//	func LoadConfig() (Config, error)
+//	func LoadConfigFrom(lookup func(string) (string, bool)) (Config, error)
+//
+// which behave like envy.Parse[Config] over the process environment or
+// lookup. The supported tags are env, default, required, envPrefix, layout,
+// sep and kvsep; pointers, validation and secret files need envy itself, and
+// ref+ secret references and ENC[...] values fail to load.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("envygen: ")
+
+	typeName := flag.String("type", "", "name of the config struct type (required)")
+	output := flag.String("output", "", "output file, `<type>_envy.go` in the package directory by default")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: envygen -type Config [-output file] [dir]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeName == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(*typeName)+"_envy.go")
+	}
+
+	src, err := generate(dir, *typeName, filepath.Base(*output))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
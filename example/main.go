@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/moeghifar/libgo/pkg/climd"
@@ -15,8 +16,9 @@ type Config struct {
 		Port int `env:"APP_PORT" default:"8080"`
 	}
 	Database struct {
-		DSN  string `env:"DB_DSN"`
-		Pool int    `env:"DB_POOL" default:"10"`
+		DSN      string `env:"DB_DSN"`
+		Password string `env:"DB_PASSWORD" secret:"true"`
+		Pool     int    `env:"DB_POOL" default:"10"`
 	}
 	AllowedHosts []string `env:"ALLOWED_HOSTS" default:"localhost,127.0.0.1"`
 	MustExist    int      `env:"MUST_EXIST" default:"999" required:"true"`
@@ -30,12 +32,9 @@ func main() {
 		panic("Failed to load configuration: " + err.Error())
 	}
 
+	// Secrets and passwords in URLs like the DSN are masked
 	fmt.Printf("Loaded Configuration:\n")
-	fmt.Printf("Port: %d\n", cfg.App.Port)
-	fmt.Printf("DSN: %s\n", cfg.Database.DSN)
-	fmt.Printf("Pool: %d\n", cfg.Database.Pool)
-	fmt.Printf("Allowed Hosts: %v\n", cfg.AllowedHosts)
-	fmt.Printf("Must Exist: %v\n", cfg.MustExist)
+	envy.Dump(&cfg, os.Stdout)
 
 	appRunner := climd.AppConfig{
 		Name:        "myapp",
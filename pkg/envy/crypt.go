@@ -0,0 +1,241 @@
+package envy
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/moeghifar/libgo/pkg/envy/envyrt"
+)
+
+// Encrypted values look like ENC[AES256_GCM,<base64 nonce and ciphertext>].
+const (
+	encPrefix = "ENC[AES256_GCM,"
+	encSuffix = "]"
+)
+
+// defaultKeyEnv names the variable holding the decryption key unless
+// WithKeyEnv or WithKeyFile say otherwise.
+const defaultKeyEnv = "ENVY_KEY"
+
+// errDecrypt hides why decryption failed, which could hint at the key or
+// plaintext.
+var errDecrypt = errors.New("wrong key, or the value was tampered with or encrypted for another key name")
+
+// WithKeyFile reads the key of encrypted values from path, holding a key
+// made by GenerateKey.
+func WithKeyFile(path string) Option {
+	return func(o *options) {
+		o.keyFile = path
+	}
+}
+
+// WithKeyEnv reads the key of encrypted values from the variable name,
+// ENVY_KEY by default. It is looked up in the sources like any value.
+func WithKeyEnv(name string) Option {
+	return func(o *options) {
+		o.keyEnv = name
+	}
+}
+
+// GenerateKey returns a new random key, base64 encoded, for Encrypt and
+// WithKeyFile.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypts the value of the variable name with AES-256-GCM. The
+// name is authenticated too, so the value can't be moved to another key.
+func Encrypt(key, name, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed) + encSuffix, nil
+}
+
+// Decrypt returns the plaintext of value, encrypted by Encrypt for the
+// variable name. Errors never include the value.
+func Decrypt(key, name, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if !isEncrypted(value) {
+		return "", errors.New("not an ENC[AES256_GCM,...] value")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encPrefix), encSuffix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", errDecrypt
+	}
+	return string(plaintext), nil
+}
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("invalid key: expected 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptEnv encrypts the plaintext values of names in .env content,
+// rewriting their lines as NAME=ENC[...]. What gets encrypted is the value
+// Load reads, escapes such as $$ resolved, since decrypted values are
+// taken verbatim. Values already encrypted are left alone, multiline
+// values and values with ${VAR} references aren't supported.
+func EncryptEnv(data []byte, key string, names ...string) ([]byte, error) {
+	return rewriteEnv(data, func(e dotenvEntry) (string, bool, error) {
+		if !slices.Contains(names, e.Key) || isEncrypted(e.Value) {
+			return "", false, nil
+		}
+		if strings.Contains(e.Value, "\n") {
+			return "", false, fmt.Errorf("%s: multiline values can't be encrypted", e.Key)
+		}
+		plaintext, err := unescapeEnv(e)
+		if err != nil {
+			return "", false, err
+		}
+		enc, err := Encrypt(key, e.Key, plaintext)
+		return enc, true, err
+	}, names)
+}
+
+// unescapeEnv returns the value of e as Load reads it. References would be
+// frozen at their current value once encrypted, so they are refused.
+func unescapeEnv(e dotenvEntry) (string, error) {
+	expander := envyrt.NewExpander(func(name string) (string, error) {
+		return "", fmt.Errorf("%s: values referencing other variables like %s can't be encrypted", e.Key, name)
+	})
+	return expander.ExpandDotenv(e.Key, e.Value)
+}
+
+// RotateEnv re-encrypts every encrypted value of .env content with
+// newKey.
+func RotateEnv(data []byte, oldKey, newKey string) ([]byte, error) {
+	return rewriteEnv(data, func(e dotenvEntry) (string, bool, error) {
+		if !isEncrypted(e.Value) {
+			return "", false, nil
+		}
+		plaintext, err := Decrypt(oldKey, e.Key, e.Value)
+		if err != nil {
+			return "", false, fmt.Errorf("%s: %w", e.Key, err)
+		}
+		enc, err := Encrypt(newKey, e.Key, plaintext)
+		return enc, true, err
+	}, nil)
+}
+
+// rewriteEnv replaces the value of each entry of .env content for which
+// fn returns a new value, keeping the rest of the file as is. Every key in
+// want must be assigned in the content.
+func rewriteEnv(data []byte, fn func(dotenvEntry) (string, bool, error), want []string) ([]byte, error) {
+	entries, err := parseDotenvEntries(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	seen := map[string]bool{}
+	for _, e := range entries {
+		seen[e.Key] = true
+		value, ok, err := fn(e)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		line := lines[e.Line-1]
+		prefix := ""
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "export" {
+			prefix = "export "
+		}
+		lines[e.Line-1] = prefix + e.Key + "=" + value
+		if strings.HasSuffix(line, "\r") {
+			lines[e.Line-1] += "\r"
+		}
+	}
+
+	for _, name := range want {
+		if !seen[name] {
+			return nil, fmt.Errorf("%s is not set in the file", name)
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// decrypt decrypts an encrypted value read for key, loading the key of
+// the parser on first use.
+func (p *parser) decrypt(key, value string) (string, error) {
+	if p.key == "" {
+		k, err := p.loadKey()
+		if err != nil {
+			return "", err
+		}
+		p.key = k
+	}
+
+	plaintext, err := Decrypt(p.key, key, value)
+	if err != nil {
+		return "", fmt.Errorf("decrypting value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// loadKey reads the key from the WithKeyFile file, or the key variable.
+func (p *parser) loadKey() (string, error) {
+	if p.opts.keyFile != "" {
+		data, err := os.ReadFile(p.opts.keyFile)
+		if err != nil {
+			return "", fmt.Errorf("reading key file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	name := p.opts.keyVar()
+	key, ok := p.lookupValue(name)
+	if !ok || key == "" {
+		return "", fmt.Errorf("value is encrypted but no key is set, set %s or use WithKeyFile", name)
+	}
+	return key, nil
+}
+
+// keyVar returns the name of the variable holding the key.
+func (o *options) keyVar() string {
+	if o.keyEnv == "" {
+		return defaultKeyEnv
+	}
+	return o.keyEnv
+}
@@ -0,0 +1,209 @@
+package envy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) string {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := testKey(t)
+	enc, err := Encrypt(key, "DB_PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enc, "ENC[AES256_GCM,") || strings.Contains(enc, "hunter2") {
+		t.Fatalf("expected an ENC[AES256_GCM,...] value without the plaintext, got %q", enc)
+	}
+	if again, _ := Encrypt(key, "DB_PASSWORD", "hunter2"); again == enc {
+		t.Error("expected random nonces, got the same value twice")
+	}
+
+	if got, err := Decrypt(key, "DB_PASSWORD", enc); err != nil || got != "hunter2" {
+		t.Errorf("expected hunter2, got %q (error: %v)", got, err)
+	}
+
+	// The key name is authenticated, values can't be swapped between keys
+	for name, k := range map[string]string{"API_TOKEN": key, "DB_PASSWORD": testKey(t)} {
+		_, err := Decrypt(k, name, enc)
+		if err == nil || strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), enc[15:30]) {
+			t.Errorf("expected an error without the value decrypting as %s, got %v", name, err)
+		}
+	}
+	if _, err := Decrypt(key, "DB_PASSWORD", "ENC[AES256_GCM,!!]"); err == nil {
+		t.Error("expected an error for a malformed value")
+	}
+	if _, err := Encrypt("short", "K", "v"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+type encryptedConfig struct {
+	Password string `env:"E_PASSWORD"`
+	Port     int    `env:"E_PORT"`
+	Host     string `env:"E_HOST"`
+}
+
+func TestLoad_Encrypted(t *testing.T) {
+	key := testKey(t)
+	password, _ := Encrypt(key, "E_PASSWORD", "hunter2")
+	port, _ := Encrypt(key, "E_PORT", "5433")
+
+	dir := t.TempDir()
+	env := "# production\nE_HOST=db\nE_PASSWORD=" + password + "\nE_PORT=\"" + port + "\"\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg encryptedConfig
+	var report Report
+	if err := Load(&cfg, WithEnvDir(dir), WithOverlay(), WithKeyFile(keyFile), WithReport(&report)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Password != "hunter2" || cfg.Port != 5433 || cfg.Host != "db" {
+		t.Errorf("expected decrypted values, got %+v", cfg)
+	}
+	if out := report.String(); strings.Contains(out, "hunter2") || !strings.Contains(out, "(decrypted)") {
+		t.Errorf("expected masked, decrypted values in the report, got:\n%s", out)
+	}
+	// The report of the load marks decrypted values to be masked
+	if redacted := Redacted(&cfg, WithReport(&report)); redacted["Password"] != mask || redacted["Port"] != mask || redacted["Host"] != "db" {
+		t.Errorf("expected decrypted values to be masked, got %v", redacted)
+	}
+
+	// The key can also come from a variable
+	cfg = encryptedConfig{}
+	if err := Load(&cfg, WithKeyEnv("E_KEY"), WithSources(MapSource(map[string]string{"E_KEY": key, "E_PASSWORD": password}))); err != nil || cfg.Password != "hunter2" {
+		t.Errorf("expected the key from E_KEY to decrypt, got %+v (error: %v)", cfg, err)
+	}
+}
+
+func TestLoad_EncryptEnvKeepsValues(t *testing.T) {
+	key := testKey(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	env := "C_ESCAPED=pa$$word\nC_GODOTENV=\"\\$5\"\nC_LITERAL='${HOME}$'\nC_LONE=5$ off\n"
+	if err := os.WriteFile(path, []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Escaped  string `env:"C_ESCAPED"`
+		Godotenv string `env:"C_GODOTENV"`
+		Literal  string `env:"C_LITERAL"`
+		Lone     string `env:"C_LONE"`
+	}
+	plain, err := Parse[config](WithEnvDir(dir), WithOverlay())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if plain != (config{"pa$word", "$5", "${HOME}$", "5$ off"}) {
+		t.Fatalf("unexpected plaintext config %+v", plain)
+	}
+
+	enc, err := EncryptEnv([]byte(env), key, "C_ESCAPED", "C_GODOTENV", "C_LITERAL", "C_LONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, enc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENVY_KEY", key)
+	decrypted, err := Parse[config](WithEnvDir(dir), WithOverlay())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decrypted != plain {
+		t.Errorf("expected encrypting to keep %+v, got %+v", plain, decrypted)
+	}
+}
+
+func TestLoad_EncryptedErrors(t *testing.T) {
+	key := testKey(t)
+	password, _ := Encrypt(key, "E_PASSWORD", "hunter2")
+	port, _ := Encrypt(key, "E_PORT", "not-a-port")
+
+	var cfg encryptedConfig
+	err := Load(&cfg, WithSources(MapSource(map[string]string{"E_PASSWORD": password})))
+	if err == nil || !strings.Contains(err.Error(), "var `E_PASSWORD` (Password): parse error: value is encrypted but no key is set, set ENVY_KEY or use WithKeyFile") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+
+	err = Load(&cfg, WithSources(MapSource(map[string]string{
+		"ENVY_KEY":   testKey(t),
+		"E_PASSWORD": password,
+		"E_HOST":     password, // Encrypted for another key name
+	})))
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	for _, e := range errs.Errs {
+		if msg := e.Error(); strings.Contains(msg, "hunter2") || strings.Contains(msg, password) || e.Value != mask {
+			t.Errorf("expected the value to be left out, got %v (%q)", msg, e.Value)
+		}
+	}
+
+	err = Load(&cfg, WithSources(MapSource(map[string]string{"ENVY_KEY": key, "E_PORT": port})))
+	if err == nil || strings.Contains(err.Error(), "not-a-port") || !strings.Contains(err.Error(), "E_PORT") {
+		t.Errorf("expected an E_PORT error without the value, got %v", err)
+	}
+}
+
+func TestEncryptEnvAndRotate(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	data := []byte("# comment\nexport DB_PASSWORD='hunter2' # inline\nDB_HOST=db\r\nAPI_TOKEN=\"tok\\\"en\"\n")
+
+	enc, err := EncryptEnv(data, oldKey, "DB_PASSWORD", "API_TOKEN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(enc), "\n")
+	if lines[0] != "# comment" || !strings.HasPrefix(lines[1], "export DB_PASSWORD=ENC[") || lines[2] != "DB_HOST=db\r" || !strings.HasPrefix(lines[3], "API_TOKEN=ENC[") {
+		t.Fatalf("expected the values encrypted in place, got:\n%s", enc)
+	}
+
+	// Encrypting twice leaves encrypted values alone
+	if again, err := EncryptEnv(enc, oldKey, "DB_PASSWORD"); err != nil || string(again) != string(enc) {
+		t.Errorf("expected encrypted values to be left alone, got %v", err)
+	}
+	if _, err := EncryptEnv(data, oldKey, "MISSING"); err == nil || err.Error() != "MISSING is not set in the file" {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+
+	if _, err := EncryptEnv([]byte("DSN=postgres://${DB_HOST}/app\n"), oldKey, "DSN"); err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+		t.Errorf("expected an error for a value with a reference, got %v", err)
+	}
+
+	rotated, err := RotateEnv(enc, oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := parseDotenv(strings.NewReader(string(rotated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"DB_PASSWORD": "hunter2", "API_TOKEN": `tok"en`} {
+		if got, err := Decrypt(newKey, name, values[name]); err != nil || got != want {
+			t.Errorf("expected %s to be %q, got %q (error: %v)", name, want, got, err)
+		}
+	}
+	if _, err := RotateEnv(enc, newKey, oldKey); err == nil || !strings.HasPrefix(err.Error(), "DB_PASSWORD: ") {
+		t.Errorf("expected a DB_PASSWORD error with the wrong key, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package envy
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]func(string) (any, error){}
+)
+
+// RegisterDecoder teaches envy how to parse values of type t. The decoder
+// must return a value assignable to t. Registered decoders take precedence
+// over the built-in parsing and encoding.TextUnmarshaler, for both fields
+// and slice elements. Registering a nil decoder removes it.
+func RegisterDecoder(t reflect.Type, decode func(string) (any, error)) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	// A struct type with a decoder is no longer walked field by field, the
+	// compiled plans may be stale
+	plans.Clear()
+
+	if decode == nil {
+		delete(decoders, t)
+		return
+	}
+	decoders[t] = decode
+}
+
+func lookupDecoder(t reflect.Type) (func(string) (any, error), bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	decode, ok := decoders[t]
+	return decode, ok
+}
+
+// isValueType reports whether t is decoded from a single env value rather
+// than walked field by field, which matters for struct types.
+func isValueType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	if _, ok := lookupDecoder(t); ok {
+		return true
+	}
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// decodeRegistered runs the registered decoder for the field type, if any.
+// It reports false when no decoder is registered.
+func decodeRegistered(field reflect.Value, value string) (bool, error) {
+	decode, ok := lookupDecoder(field.Type())
+	if !ok {
+		return false, nil
+	}
+
+	decoded, err := decode(value)
+	if err != nil {
+		return true, fmt.Errorf("invalid %v: %w", field.Type(), err)
+	}
+	rv := reflect.ValueOf(decoded)
+	if !rv.IsValid() || !rv.Type().AssignableTo(field.Type()) {
+		return true, fmt.Errorf("decoder for %v returned %T", field.Type(), decoded)
+	}
+	field.Set(rv)
+	return true, nil
+}
+
+// decodeText calls UnmarshalText when the field implements
+// encoding.TextUnmarshaler. It reports false when it does not.
+func decodeText(field reflect.Value, value string) (bool, error) {
+	if !field.CanAddr() {
+		return false, nil
+	}
+	u, ok := field.Addr().Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return false, nil
+	}
+
+	if err := u.UnmarshalText([]byte(value)); err != nil {
+		return true, fmt.Errorf("invalid %v: %w", field.Type(), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,100 @@
+package envy
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	case "error":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type tenantID struct {
+	Region string
+	ID     string
+}
+
+func TestLoad_TextUnmarshaler(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("AUDIT_LEVELS", "debug, info")
+	t.Setenv("BIND_IP", "10.0.0.1")
+
+	var cfg struct {
+		Level  logLevel `env:"LOG_LEVEL"`
+		Nested struct {
+			Levels []logLevel `env:"AUDIT_LEVELS"`
+			IP     net.IP     `env:"BIND_IP"`
+		}
+	}
+	if err := parse(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Level != 2 {
+		t.Errorf("expected level 2, got %d", cfg.Level)
+	}
+	if !reflect.DeepEqual(cfg.Nested.Levels, []logLevel{0, 1}) {
+		t.Errorf("expected levels [0 1], got %v", cfg.Nested.Levels)
+	}
+	if !cfg.Nested.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("expected ip 10.0.0.1, got %v", cfg.Nested.IP)
+	}
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	if err := parse(&cfg); err == nil {
+		t.Fatal("expected error for unknown level, got nil")
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	typ := reflect.TypeOf(tenantID{})
+	RegisterDecoder(typ, func(s string) (any, error) {
+		region, id, ok := strings.Cut(s, "/")
+		if !ok {
+			return nil, fmt.Errorf("expected region/id, got %q", s)
+		}
+		return tenantID{Region: region, ID: id}, nil
+	})
+	defer RegisterDecoder(typ, nil)
+
+	t.Setenv("TENANT", "eu/acme")
+	t.Setenv("TENANTS", "eu/acme,us/globex")
+
+	var cfg struct {
+		Tenant tenantID `env:"TENANT"`
+		Nested struct {
+			Tenants []tenantID `env:"TENANTS"`
+		}
+	}
+	if err := parse(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Tenant != (tenantID{Region: "eu", ID: "acme"}) {
+		t.Errorf("unexpected tenant %+v", cfg.Tenant)
+	}
+	expected := []tenantID{{"eu", "acme"}, {"us", "globex"}}
+	if !reflect.DeepEqual(cfg.Nested.Tenants, expected) {
+		t.Errorf("expected tenants %v, got %v", expected, cfg.Nested.Tenants)
+	}
+
+	t.Setenv("TENANT", "acme")
+	if err := parse(&cfg); err == nil {
+		t.Fatal("expected decoder error, got nil")
+	}
+}
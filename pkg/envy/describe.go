@@ -0,0 +1,153 @@
+package envy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// FieldInfo describes a config field, as read from its tags.
+type FieldInfo struct {
+	Key      string `json:"key"`
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required"`
+	Desc     string `json:"desc,omitempty"`
+}
+
+// Describe walks the tags of target, a struct or a pointer to one, the
+// same way Load does and returns the metadata of every field with an env
+// tag, in declaration order.
+func Describe(target any) ([]FieldInfo, error) {
+	typ := reflect.TypeOf(target)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("target must be a struct or a pointer to a struct")
+	}
+
+	var fields []FieldInfo
+	walkPlan(planFor(typ).fields, func(fp *fieldPlan) {
+		fields = append(fields, FieldInfo{
+			Key:      fp.key,
+			Field:    fp.path,
+			Type:     fp.structField.Type.String(),
+			Default:  fp.def,
+			Required: fp.required,
+			Desc:     fp.structField.Tag.Get("desc"),
+		})
+	})
+	return fields, nil
+}
+
+// WriteExample renders fields as a .env.example file, each key set to its
+// default with its description and type in a comment above.
+func WriteExample(w io.Writer, fields []FieldInfo) error {
+	for i, f := range fields {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+
+		var b strings.Builder
+		if f.Desc != "" {
+			fmt.Fprintf(&b, "# %s\n", f.Desc)
+		}
+		fmt.Fprintf(&b, "# type: %s", f.Type)
+		if f.Required {
+			b.WriteString(", required")
+		}
+		fmt.Fprintf(&b, "\n%s=%s\n", f.Key, exampleValue(f.Default))
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exampleValue quotes defaults that the dotenv parsers would otherwise
+// trim or cut at a comment.
+func exampleValue(s string) string {
+	if s == "" || !strings.ContainsAny(s, " \t#\"'\\\n") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteMarkdown renders fields as a Markdown reference table.
+func WriteMarkdown(w io.Writer, fields []FieldInfo) error {
+	var b strings.Builder
+	b.WriteString("| Key | Type | Default | Required | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, f := range fields {
+		def := ""
+		if f.Default != "" {
+			def = "`" + markdownCell(f.Default) + "`"
+		}
+		required := "no"
+		if f.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s |\n", f.Key, markdownCell(f.Type), def, required, markdownCell(f.Desc))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteJSON renders fields as an indented JSON array.
+func WriteJSON(w io.Writer, fields []FieldInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fields)
+}
+
+// Verify checks that the example file at path declares every key of
+// target, so it can run in CI and catch .env.example drifting from the
+// config struct.
+func Verify(path string, target any) error {
+	fields, err := Describe(target)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer f.Close()
+
+	entries, err := parseDotenvEntries(f)
+	if err != nil {
+		if derr, ok := err.(*DotenvError); ok {
+			derr.File = path
+		}
+		return err
+	}
+
+	declared := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		declared[e.Key] = true
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if !declared[f.Key] {
+			missing = append(missing, f.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing %d keys: %s", path, len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
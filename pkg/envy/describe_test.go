@@ -0,0 +1,121 @@
+package envy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type describedConfig struct {
+	Port    int           `env:"PORT" default:"8080" desc:"HTTP listen port"`
+	APIKey  string        `env:"API_KEY" required:"true" desc:"Key for the billing API"`
+	Timeout time.Duration `env:"TIMEOUT" default:"30s"`
+	Replica *struct {
+		DSN string `env:"DSN" desc:"Replica | read only"`
+	} `envPrefix:"REPLICA_"`
+	Greeting string `env:"GREETING" default:"hello world"`
+	Ignored  string
+}
+
+func TestDescribe(t *testing.T) {
+	fields, err := Describe(&describedConfig{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []FieldInfo{
+		{Key: "PORT", Field: "Port", Type: "int", Default: "8080", Desc: "HTTP listen port"},
+		{Key: "API_KEY", Field: "APIKey", Type: "string", Required: true, Desc: "Key for the billing API"},
+		{Key: "TIMEOUT", Field: "Timeout", Type: "time.Duration", Default: "30s"},
+		{Key: "REPLICA_DSN", Field: "Replica.DSN", Type: "string", Desc: "Replica | read only"},
+		{Key: "GREETING", Field: "Greeting", Type: "string", Default: "hello world"},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %+v, got %+v", expected, fields)
+	}
+
+	if _, err := Describe(42); err == nil {
+		t.Error("expected error for non-struct target")
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	fields, _ := Describe(describedConfig{})
+
+	var example bytes.Buffer
+	if err := WriteExample(&example, fields); err != nil {
+		t.Fatal(err)
+	}
+	expectedExample := `# HTTP listen port
+# type: int
+PORT=8080
+
+# Key for the billing API
+# type: string, required
+API_KEY=
+
+# type: time.Duration
+TIMEOUT=30s
+
+# Replica | read only
+# type: string
+REPLICA_DSN=
+
+# type: string
+GREETING="hello world"
+`
+	if example.String() != expectedExample {
+		t.Errorf("unexpected example:\n%s", example.String())
+	}
+
+	// The example must read back as the defaults
+	values, err := parseDotenv(&example)
+	if err != nil || values["GREETING"] != "hello world" {
+		t.Errorf("expected example to parse back, got %v %v", values, err)
+	}
+
+	var md bytes.Buffer
+	if err := WriteMarkdown(&md, fields); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "| `REPLICA_DSN` | `string` |  | no | Replica \\| read only |\n") {
+		t.Errorf("unexpected markdown:\n%s", md.String())
+	}
+
+	var js bytes.Buffer
+	if err := WriteJSON(&js, fields); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []FieldInfo
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, fields) {
+		t.Errorf("expected json to round-trip, got %v %v", decoded, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.example")
+	content := "PORT=8080\nAPI_KEY=\n# TIMEOUT=30s\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Verify(path, &describedConfig{})
+	if err == nil || !strings.Contains(err.Error(), "missing 3 keys: TIMEOUT, REPLICA_DSN, GREETING") {
+		t.Errorf("expected missing keys error, got %v", err)
+	}
+
+	var full bytes.Buffer
+	fields, _ := Describe(&describedConfig{})
+	WriteExample(&full, fields)
+	if err := os.WriteFile(path, full.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(path, &describedConfig{}); err != nil {
+		t.Errorf("expected generated example to verify, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package envy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DotenvError reports a syntax error in a .env file read by the built-in
+// parser.
+type DotenvError struct {
+	File string // Empty when parsing a reader
+	Line int
+	Msg  string
+}
+
+func (e *DotenvError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// dotenvEntry is a single assignment, Line is where its key appears.
+type dotenvEntry struct {
+	Key     string
+	Value   string
+	Line    int
+	Literal bool // Single quoted, dollar signs are doubled in Value
+}
+
+// dotenvLines maps each key of .env content to the line assigning it last.
+// It is best effort, content the built-in parser rejects has no lines.
+func dotenvLines(data []byte) map[string]int {
+	entries, _ := parseDotenvEntries(bytes.NewReader(data))
+	lines := make(map[string]int, len(entries))
+	for _, e := range entries {
+		lines[e.Key] = e.Line
+	}
+	return lines
+}
+
+// parseDotenv parses .env content into a map, later assignments win.
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	entries, err := parseDotenvEntries(r)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, e := range entries {
+		values[e.Key] = e.Value
+	}
+	return values, nil
+}
+
+// parseDotenvEntries is the built-in .env parser. It supports comments,
+// an optional `export` prefix, unquoted values with trailing comments,
+// single quoted literal values and double quoted values with escape
+// sequences, both of which may span lines. ${VAR} references are kept
+// as is, envy expands them when a field reads the value, and \$ in
+// unquoted or double quoted values becomes $$ as a literal dollar. Dollar
+// signs of single quoted values are doubled so they stay literal.
+func parseDotenvEntries(r io.Reader) ([]dotenvEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var entries []dotenvEntry
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimLeft(lines[i], " \t")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimLeft(rest, " \t")
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, &DotenvError{Line: lineNo, Msg: fmt.Sprintf("expected KEY=value, got %q", strings.TrimSpace(line))}
+		}
+		if !validDotenvKey(key) {
+			return nil, &DotenvError{Line: lineNo, Msg: fmt.Sprintf("invalid key %q", key)}
+		}
+
+		rest := strings.TrimLeft(raw, " \t")
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			value := replaceEscapedDollars(unquotedValue(raw), "$$")
+			entries = append(entries, dotenvEntry{Key: key, Value: value, Line: lineNo})
+			continue
+		}
+
+		// Quoted values run until the closing quote, possibly on a later line
+		quote := rest[0]
+		body := rest[1:]
+		var b strings.Builder
+		for {
+			end := closingQuote(body, quote)
+			if end >= 0 {
+				b.WriteString(body[:end])
+				if trailing := strings.TrimSpace(body[end+1:]); trailing != "" && trailing[0] != '#' {
+					return nil, &DotenvError{Line: i + 1, Msg: fmt.Sprintf("unexpected %q after quoted value", trailing)}
+				}
+				break
+			}
+			b.WriteString(body)
+			b.WriteByte('\n')
+			i++
+			if i == len(lines) {
+				return nil, &DotenvError{Line: lineNo, Msg: fmt.Sprintf("unterminated quoted value for %s", key)}
+			}
+			body = lines[i]
+		}
+
+		value := b.String()
+		if quote == '"' {
+			value = unescape(value)
+		} else {
+			value = strings.ReplaceAll(value, "$", "$$")
+		}
+		entries = append(entries, dotenvEntry{Key: key, Value: value, Line: lineNo, Literal: quote == '\''})
+	}
+	return entries, nil
+}
+
+// unquotedValue trims an unquoted value and drops a trailing comment, which
+// must be preceded by whitespace so values like #fff survive.
+func unquotedValue(raw string) string {
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i > 0 && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			raw = raw[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(raw)
+}
+
+// closingQuote returns the index of the quote ending s, skipping escaped
+// quotes inside double quoted values, or -1.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescape resolves the escape sequences of double quoted values, unknown
+// sequences are kept as is. \$ becomes $$, a literal dollar for envy.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case '$':
+			b.WriteString("$$")
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// replaceEscapedDollars replaces \$, godotenv's literal dollar, with
+// dollar. A backslash escaped itself doesn't escape the dollar after it.
+func replaceEscapedDollars(s, dollar string) string {
+	if !strings.Contains(s, `\$`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] != '\\' || i+1 == len(s):
+			b.WriteByte(s[i])
+		case s[i+1] == '$':
+			b.WriteString(dollar)
+			i++
+		default:
+			b.WriteString(s[i : i+2])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func validDotenvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		if c == '_' || c == '.' && i > 0 || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
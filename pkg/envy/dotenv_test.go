@@ -0,0 +1,103 @@
+package envy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	content := `# database settings
+DB_HOST=localhost
+export DB_PORT = 5432
+DB_NAME=app # trailing comment
+COLOR=#fff
+EMPTY=
+SINGLE='raw \n ${NOT_EXPANDED} \$'
+ESCAPED=\$5 "\$"
+DOUBLE="tab\there \"quoted\" \\ done"
+URL="postgres://${DB_HOST}/app"
+CERT="-----BEGIN-----
+line two
+-----END-----"
+  INDENTED=yes
+WINDOWS=crlf` + "\r\n"
+
+	values, err := parseDotenv(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "5432",
+		"DB_NAME":  "app",
+		"COLOR":    "#fff",
+		"EMPTY":    "",
+		"SINGLE":   `raw \n $${NOT_EXPANDED} \$$`,
+		"ESCAPED":  `$$5 "$$"`,
+		"DOUBLE":   "tab\there \"quoted\" \\ done",
+		"URL":      "postgres://${DB_HOST}/app",
+		"CERT":     "-----BEGIN-----\nline two\n-----END-----",
+		"INDENTED": "yes",
+		"WINDOWS":  "crlf",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestParseDotenv_SyntaxErrors(t *testing.T) {
+	cases := []struct {
+		content string
+		line    int
+	}{
+		{"A=1\nNOT AN ASSIGNMENT\n", 2},
+		{"A=1\n\n1BAD=2\n", 3},
+		{"A=1\nB=\"never closed\nC=3\n", 2},
+		{"A='quoted' trailing\n", 1},
+	}
+	for _, c := range cases {
+		_, err := parseDotenv(strings.NewReader(c.content))
+		var derr *DotenvError
+		if !errors.As(err, &derr) {
+			t.Errorf("%q: expected *DotenvError, got %v", c.content, err)
+			continue
+		}
+		if derr.Line != c.line {
+			t.Errorf("%q: expected line %d, got %d (%v)", c.content, c.line, derr.Line, err)
+		}
+	}
+}
+
+func TestLoad_NativeDotenv(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("N_USER=app\nN_DSN=\"postgres://${N_USER}@db/app\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("N_USER")
+		os.Unsetenv("N_DSN")
+	})
+
+	var cfg struct {
+		DSN string `env:"N_DSN"`
+	}
+	if err := Load(&cfg, WithEnvDir(dir), WithNativeDotenv()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.DSN != "postgres://app@db/app" {
+		t.Errorf("expected expanded dsn, got %q", cfg.DSN)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("N_USER=app\nN_DSN=\"oops\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Load(&cfg, WithEnvDir(dir), WithNativeDotenv())
+	if err == nil || !strings.Contains(err.Error(), ".env:2:") {
+		t.Errorf("expected syntax error with file and line, got %v", err)
+	}
+}
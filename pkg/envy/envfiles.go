@@ -0,0 +1,120 @@
+package envy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moeghifar/libgo/pkg/envy/envyrt"
+)
+
+// envPlaceholder is replaced by the active environment in .env file names.
+const envPlaceholder = "{env}"
+
+// defaultEnvFiles is the standard precedence chain, lowest first.
+var defaultEnvFiles = []string{".env", ".env." + envPlaceholder, ".env.local", ".env." + envPlaceholder + ".local"}
+
+// WithEnvFiles sets the .env file names, lowest precedence first. A
+// "{env}" placeholder is replaced by the active environment and names
+// using it are skipped when no environment is active. The default is
+// .env, .env.{env}, .env.local and .env.{env}.local.
+func WithEnvFiles(names ...string) Option {
+	return func(o *options) {
+		o.envFiles = names
+	}
+}
+
+// WithEnvDir sets the directory .env files are searched in, the working
+// directory by default.
+func WithEnvDir(dir string) Option {
+	return func(o *options) {
+		o.envDir = dir
+	}
+}
+
+// WithEnvVar sets the process env var naming the active environment,
+// APP_ENV by default.
+func WithEnvVar(name string) Option {
+	return func(o *options) {
+		o.envVar = name
+	}
+}
+
+// Files returns the .env files Load reads for the active environment,
+// lowest precedence first. Missing files are left out.
+func (l *Loader) Files() ([]string, error) {
+	return newOptions(l.opts).existingEnvFiles()
+}
+
+// existingEnvFiles resolves the .env file chain against the active
+// environment and keeps the files that exist.
+func (o *options) existingEnvFiles() ([]string, error) {
+	env := os.Getenv(o.envVar)
+
+	var files []string
+	for _, name := range o.envFiles {
+		if strings.Contains(name, envPlaceholder) {
+			if env == "" {
+				continue
+			}
+			name = strings.ReplaceAll(name, envPlaceholder, env)
+		}
+
+		path := filepath.Join(o.envDir, name)
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// readEnvFiles reads the .env file chain into a single source, later files
+// overriding earlier ones. It also returns the files read.
+func (o *options) readEnvFiles() (*dotenvSource, []string, error) {
+	files, err := o.existingEnvFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := &dotenvSource{values: map[string]string{}, origins: map[string]Origin{}}
+	for _, file := range files {
+		src, err := o.readEnvFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		merged.merge(src)
+	}
+	return merged, files, nil
+}
+
+// exportEnv sets the values in the process environment with their
+// references expanded, as godotenv exported them, variables that are
+// already set always win. Load still expands the values as written, so
+// references can fall back on the defaults of the config. Values that
+// can't be expanded without those defaults are exported as written,
+// their errors are Load's to report.
+func exportEnv(src *dotenvSource) error {
+	p := &parser{opts: &options{resolved: []Source{EnvSource(), src}}, plan: &plan{}, used: map[string]bool{}}
+	p.expander = envyrt.NewExpander(p.expandedValue)
+	for k, raw := range src.values {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		v, err := p.expand(k, raw, true)
+		if err != nil {
+			v = raw
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("error loading %s: %w", k, err)
+		}
+		exported.Store(k, exportedValue{value: v, raw: raw, origin: src.origins[k]})
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package envy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_LayeredEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env":               "L_A=base\nL_B=base\nL_C=base\nL_D=base\nL_E=base\n",
+		".env.staging":       "L_B=staging\nL_C=staging\nL_D=staging\n",
+		".env.local":         "L_C=local\nL_D=local\n",
+		".env.staging.local": "L_D=staging-local\n",
+		".env.production":    "L_A=production\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DEPLOY_ENV", "staging")
+	t.Setenv("L_E", "process")
+	for _, key := range []string{"L_A", "L_B", "L_C", "L_D"} {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+
+	loader := New(WithEnvDir(dir), WithEnvVar("DEPLOY_ENV"))
+
+	loaded, err := loader.Files()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedFiles := []string{
+		filepath.Join(dir, ".env"),
+		filepath.Join(dir, ".env.staging"),
+		filepath.Join(dir, ".env.local"),
+		filepath.Join(dir, ".env.staging.local"),
+	}
+	if !reflect.DeepEqual(loaded, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, loaded)
+	}
+
+	var cfg struct {
+		A string `env:"L_A"`
+		B string `env:"L_B"`
+		C string `env:"L_C"`
+		D string `env:"L_D"`
+		E string `env:"L_E"`
+	}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := [5]string{"base", "staging", "local", "staging-local", "process"}
+	if got := [5]string{cfg.A, cfg.B, cfg.C, cfg.D, cfg.E}; got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestLoader_FilesWithoutEnv(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.env", "app.local.env", "app.production.env"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("APP_ENV", "")
+
+	files, err := New(WithEnvDir(dir), WithEnvFiles("app.env", "app.{env}.env", "app.local.env")).Files()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{filepath.Join(dir, "app.env"), filepath.Join(dir, "app.local.env")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+}
+
+func TestLoad_Overlay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("O_PORT=9090\nO_NAME=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("O_NAME", "process")
+
+	var cfg struct {
+		Port int    `env:"O_PORT"`
+		Name string `env:"O_NAME"`
+	}
+	if err := Load(&cfg, WithEnvDir(dir), WithOverlay()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Port != 9090 || cfg.Name != "process" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if _, ok := os.LookupEnv("O_PORT"); ok {
+		t.Fatal("expected overlay values not to be exported")
+	}
+
+	t.Cleanup(func() { os.Unsetenv("O_PORT") })
+	if err := Apply(WithEnvDir(dir)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if os.Getenv("O_PORT") != "9090" || os.Getenv("O_NAME") != "process" {
+		t.Errorf("expected Apply to export without overriding, got %q %q", os.Getenv("O_PORT"), os.Getenv("O_NAME"))
+	}
+}
+
+func TestLoad_ExportsExpandedValues(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := "X_HOST=db\nX_DSN=postgres://${X_HOST}/app\nX_PRICE=\\$5\nX_QUOTED=\"\\${X_HOST}\"\nX_URL=${X_BASE}/v1\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"X_HOST", "X_DSN", "X_PRICE", "X_QUOTED", "X_URL"}
+	for _, key := range keys {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+
+	for _, native := range []bool{false, true} {
+		for _, key := range keys {
+			os.Unsetenv(key)
+		}
+		opts := []Option{WithEnvDir(dir)}
+		if native {
+			opts = append(opts, WithNativeDotenv())
+		}
+		var cfg struct {
+			DSN    string `env:"X_DSN"`
+			Price  string `env:"X_PRICE"`
+			Quoted string `env:"X_QUOTED"`
+			URL    string `env:"X_URL"`
+			Base   string `env:"X_BASE" default:"http://api"`
+		}
+		if err := Load(&cfg, opts...); err != nil {
+			t.Fatalf("expected no error (native: %v), got %v", native, err)
+		}
+
+		// Child processes see the values expanded, without the defaults of
+		// the config
+		expected := map[string]string{"X_DSN": "postgres://db/app", "X_PRICE": "$5", "X_QUOTED": "${X_HOST}", "X_URL": "/v1"}
+		for key, value := range expected {
+			if got := os.Getenv(key); got != value {
+				t.Errorf("expected %s=%q to be exported (native: %v), got %q", key, value, native, got)
+			}
+		}
+		if cfg.DSN != "postgres://db/app" || cfg.Price != "$5" || cfg.Quoted != "${X_HOST}" || cfg.URL != "http://api/v1" {
+			t.Errorf("expected the values as written to be expanded once (native: %v), got %+v", native, cfg)
+		}
+
+		// Loading again reads the exported values like the file
+		var again struct {
+			Quoted string `env:"X_QUOTED"`
+		}
+		if err := Load(&again, opts...); err != nil || again.Quoted != "${X_HOST}" {
+			t.Errorf("expected ${X_HOST} again (native: %v), got %q, %v", native, again.Quoted, err)
+		}
+	}
+}
+
+func TestLoad_ExportFallsBackOnDefaults(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := "P_DSN=pg://${P_HOST:?need host}/db\nP_LOOP=${P_LOOP}\nP_CHECK=${P_MISSING:?not set}\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"P_DSN", "P_LOOP", "P_CHECK"} {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+
+	// Keys the config doesn't read don't fail the load, references to
+	// defaults are expanded by Load
+	var cfg struct {
+		DSN  string `env:"P_DSN"`
+		Host string `env:"P_HOST" default:"dbhost"`
+	}
+	if err := Load(&cfg, WithEnvDir(dir)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.DSN != "pg://dbhost/db" {
+		t.Errorf("expected pg://dbhost/db, got %q", cfg.DSN)
+	}
+	if got := os.Getenv("P_DSN"); got != "pg://${P_HOST:?need host}/db" {
+		t.Errorf("expected P_DSN to be exported as written, got %q", got)
+	}
+
+	// Fields reading them still report their errors
+	var checked struct {
+		Check string `env:"P_CHECK"`
+	}
+	if err := Load(&checked, WithEnvDir(dir)); err == nil || !strings.Contains(err.Error(), "P_MISSING: not set") {
+		t.Errorf("expected the P_MISSING error, got %v", err)
+	}
+}
@@ -2,157 +2,514 @@ package envy
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
 	"reflect"
-	"strconv"
-	"strings"
+	"time"
+
+	"github.com/moeghifar/libgo/pkg/envy/envyrt"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
 )
 
-// Load loads environment variables from a .env file (if available)
+// Load loads environment variables from the .env files (if available)
 // and populates the target struct fields based on tags.
-func Load(target any) error {
-	// 1. Load .env file (optional, based on build tags)
-	if err := loadEnvFile(); err != nil {
-		return err
+func Load(target any, opts ...Option) error {
+	return New(opts...).Load(target)
+}
+
+// Parse loads a config of type T, which must be a struct, the same way as
+// Load:
+//
+//	cfg, err := envy.Parse[Config]()
+func Parse[T any](opts ...Option) (T, error) {
+	var cfg T
+	err := Load(&cfg, opts...)
+	return cfg, err
+}
+
+// MustParse is like Parse but panics if the config can't be loaded, for
+// use in main or package initialization.
+func MustParse[T any](opts ...Option) T {
+	cfg, err := Parse[T](opts...)
+	if err != nil {
+		panic(fmt.Sprintf("envy: loading %T: %v", cfg, err))
+	}
+	return cfg
+}
+
+// Loader loads config structs with a fixed set of options. It is safe for
+// concurrent use.
+type Loader struct {
+	opts []Option
+}
+
+// New returns a Loader configured with opts.
+func New(opts ...Option) *Loader {
+	return &Loader{opts: opts}
+}
+
+// Load populates the target struct fields based on tags.
+func (l *Loader) Load(target any) error {
+	opts := l.opts
+	o := newOptions(l.opts)
+
+	// 1. Read .env files (optional, based on build tags), unless the
+	// sources were chosen explicitly
+	var files []string
+	if len(o.sources) == 0 {
+		src, read, err := o.readEnvFiles()
+		if err != nil {
+			return err
+		}
+		files = read
+		if o.overlay {
+			// File values stay private, behind the process environment
+			opts = append(opts[:len(opts):len(opts)], WithSources(EnvSource(), src))
+		} else if err := exportEnv(src); err != nil {
+			return err
+		}
 	}
 
 	// 2. Parse struct tags and populate fields
-	return parse(target)
+	err := parse(target, opts...)
+	if o.report != nil {
+		o.report.Files = files
+	}
+	return err
+}
+
+// Apply exports the .env files into the process environment without
+// loading a struct, for use with WithOverlay when child processes or other
+// libraries need the values. Variables already set are left untouched.
+func (l *Loader) Apply() error {
+	src, _, err := newOptions(l.opts).readEnvFiles()
+	if err != nil {
+		return err
+	}
+	return exportEnv(src)
 }
 
-func parse(v any) error {
+// Apply exports the .env files into the process environment, see
+// Loader.Apply.
+func Apply(opts ...Option) error {
+	return New(opts...).Apply()
+}
+
+func parse(v any, opts ...Option) error {
 	ptrVal := reflect.ValueOf(v)
 	if ptrVal.Kind() != reflect.Ptr || ptrVal.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("target must be a pointer to a struct")
 	}
 
-	val := ptrVal.Elem()
-	typ := val.Type()
+	o := newOptions(opts)
+	pl := planFor(ptrVal.Elem().Type())
+	p := &parser{opts: o, plan: pl, used: map[string]bool{}}
+	p.expander = envyrt.NewExpander(p.reference)
+	// Invalid validation tags fail every load, not only those setting the
+	// field, so a typo doesn't wait for the variable to be set
+	walkPlan(pl.fields, func(fp *fieldPlan) {
+		if fp.tagErr != nil {
+			p.fail(fp.key, fp.path, "", fmt.Errorf("%w: %w", ErrValidation, fp.tagErr))
+		}
+	})
+	p.parseStruct(ptrVal.Elem(), pl.fields)
+	// envy's own variables are known even when not read by this load
+	p.used[o.envVar], p.used[o.keyVar()] = true, true
+	p.warnUnknown()
+	p.checkStrict(pl)
+	if o.report != nil {
+		*o.report = Report{Fields: p.fields}
+	}
+	if len(p.errs) > 0 {
+		return &Errors{Errs: p.errs}
+	}
+	return nil
+}
+
+// parser holds the state of a single parse run.
+type parser struct {
+	opts     *options
+	plan     *plan
+	expander *envyrt.Expander
+	errs     []*FieldError
+	fields   []FieldReport   // Only filled when a report is requested
+	used     map[string]bool // Every key looked up, known to the config
+	key      string          // Key of encrypted values, loaded on first use
+
+	// Set when a reference expanded to a resolved or decrypted value, which
+	// makes the value expanding it a secret too
+	refResolved, refDecrypted bool
+}
+
+// lookup reads key from the sources, also reporting the source that had it.
+// .env values envy exported are read as written in the file, they are
+// expanded again like values of files read directly.
+func (p *parser) lookup(key string) (string, Source, bool) {
+	p.used[key] = true
+	v, src, ok := lookupSources(p.opts.resolved, key)
+	if _, env := src.(envSource); env {
+		if e, ok := exportedAs(key, v); ok {
+			return e.raw, src, true
+		}
+	}
+	return v, src, ok
+}
+
+func (p *parser) lookupValue(key string) (string, bool) {
+	v, _, ok := p.lookup(key)
+	return v, ok
+}
+
+// reference returns the value a ${KEY} reference expands to, the way a
+// field reading KEY gets it: from the file named by KEY_FILE, else the
+// expanded value, then decrypted or resolved.
+func (p *parser) reference(key string) (string, error) {
+	value, fromFile, err := p.lookupFile(key, p.plan.files[key])
+	if err != nil || fromFile {
+		return value, err
+	}
+	if value, err = p.expandedValue(key); err != nil {
+		return "", err
+	}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		structField := typ.Field(i)
+	if isEncrypted(value) {
+		p.refDecrypted = true
+		if value, err = p.decrypt(key, value); err != nil {
+			return "", fmt.Errorf("%s: %w", key, err)
+		}
+		return value, nil
+	}
+	value, resolved, err := p.resolveRef(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	p.refResolved = p.refResolved || resolved
+	return value, nil
+}
 
-		// Handle nested structs (recursive)
-		if field.Kind() == reflect.Struct {
-			if err := parse(field.Addr().Interface()); err != nil {
-				return err
+// expandedValue returns the value of key, else the default of the field
+// reading it, with references expanded.
+func (p *parser) expandedValue(key string) (string, error) {
+	value, src, _ := p.lookup(key)
+	if value == "" {
+		return p.expand(key, p.plan.defaults[key], false)
+	}
+	return p.expand(key, value, originOf(src, key).Source == SourceDotenv)
+}
+
+// expand replaces the references in value, the value of key. $VAR is only
+// a reference in values read from .env files, as it was for godotenv,
+// elsewhere it may well be part of a password.
+func (p *parser) expand(key, value string, dotenv bool) (string, error) {
+	if dotenv {
+		return p.expander.ExpandDotenv(key, value)
+	}
+	return p.expander.Expand(key, value)
+}
+
+// fail records a field error, classified by envyrt.NewFieldError.
+func (p *parser) fail(key, path, value string, err error) {
+	p.errs = append(p.errs, envyrt.NewFieldError(key, path, value, err))
+}
+
+// parseStruct populates the fields of val following their plans and
+// reports whether any of its env vars (including those of nested structs)
+// were present.
+func (p *parser) parseStruct(val reflect.Value, fields []fieldPlan) bool {
+	anySet := false
+
+	for i := range fields {
+		fp := &fields[i]
+		field := val.Field(fp.index)
+
+		// Handle nested structs (recursive), unless the struct type is
+		// decoded from a single value (time.Time, TextUnmarshaler, ...)
+		if fp.nested != nil && !fp.ptr {
+			if p.parseStruct(field, fp.nested) {
+				anySet = true
 			}
 			continue
 		}
 
-		// Get tags
-		envKey := structField.Tag.Get("env")
-		defaultValue := structField.Tag.Get("default")
-		required := structField.Tag.Get("required")
+		// Nested struct pointers are only allocated when at least one of
+		// their env vars is present, otherwise they stay nil
+		if fp.ptr {
+			nested := reflect.New(field.Type().Elem())
+			errCount, fieldCount := len(p.errs), len(p.fields)
+			if !p.parseStruct(nested.Elem(), fp.nested) {
+				// Nothing provided, the whole block is optional
+				p.errs, p.fields = p.errs[:errCount], p.fields[:fieldCount]
+				continue
+			}
+			field.Set(nested)
+			anySet = true
+			continue
+		}
 
-		if envKey == "" {
-			continue // Skip fields without env tag
+		rep := FieldReport{Field: fp.path, Key: fp.key}
+		if p.parseField(field, fp, &rep) {
+			anySet = true
 		}
+		p.record(field, fp, rep)
+	}
 
-		// Get value from environment
-		envVal := os.Getenv(envKey)
+	return anySet
+}
 
-		// Use default if empty
-		if envVal == "" {
-			if required == "true" && defaultValue != "" {
-				fmt.Printf("WARNING: required env var %s not set, using default value: %s\n", envKey, defaultValue)
-			}
-			envVal = defaultValue
+// parseField populates a field with an env tag and reports whether its env
+// var was present. rep receives where the value came from.
+func (p *parser) parseField(field reflect.Value, fp *fieldPlan, rep *FieldReport) bool {
+	envKey, fieldPath := fp.key, fp.path
+	valueKey := envKey // The key the value was read from, encrypted values are bound to it
+
+	// Get value from a secret file or the sources, an empty value still
+	// counts as present
+	envVal, present, err := p.lookupFile(envKey, fp.file)
+	if err != nil {
+		p.fail(envKey, fieldPath, "", err)
+		return true
+	}
+	fromFile := present
+	if fromFile {
+		path, _ := p.lookupValue(envKey + fileSuffix)
+		rep.Origin = Origin{Source: SourceFile, File: path}
+	} else {
+		var src Source
+		var alias string
+		if envVal, src, present = p.lookup(envKey); present {
+			rep.Origin = originOf(src, envKey)
+		} else if alias, envVal, src, present = p.lookupDeprecated(fp); present {
+			rep.Origin = originOf(src, alias)
+			valueKey = alias
+		}
+	}
+
+	// An explicitly empty value sets a pointer to its zero value, it doesn't
+	// satisfy required though
+	if envVal == "" && present && field.Kind() == reflect.Ptr {
+		if fp.required {
+			p.fail(envKey, fieldPath, "", ErrRequired)
+			return true
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+		if err := p.validate(field, fp); err != nil {
+			p.fail(envKey, fieldPath, "", err)
+		}
+		return true
+	}
+
+	// Use default if empty
+	if envVal == "" && fp.def != "" {
+		if fp.required {
+			p.warn("required env var not set, using default", envKey, fieldPath,
+				slog.String("default", redactString(fp.def, fp.secret)))
 		}
+		envVal = fp.def
+		rep.Origin, rep.Default = Origin{Source: SourceDefault}, true
+	}
 
-		// Check required
-		if envVal == "" && required == "true" {
-			return fmt.Errorf("var `%s` is required", envKey)
+	// Expand ${VAR} references, then decrypt ENC[...] values or resolve
+	// ref+scheme:// secret references. Secret file contents, decrypted and
+	// resolved values are taken verbatim. References to secrets make the
+	// value a secret too
+	if !fromFile {
+		p.refResolved, p.refDecrypted = false, false
+		expanded, err := p.expand(envKey, envVal, rep.Origin.Source == SourceDotenv)
+		rep.Resolved, rep.Decrypted = p.refResolved, p.refDecrypted
+		if err != nil {
+			p.fail(envKey, fieldPath, redactString(envVal, fp.secret), err)
+			return present
 		}
+		rep.Interpolated = expanded != envVal
 
-		// Set value based on type
-		if envVal != "" {
-			if err := setField(field, envVal, structField.Name); err != nil {
-				return err
+		var resolved bool
+		if isEncrypted(expanded) {
+			rep.Decrypted = true
+			if envVal, err = p.decrypt(valueKey, expanded); err != nil {
+				p.fail(envKey, fieldPath, mask, err)
+				return present
 			}
+		} else if envVal, resolved, err = p.resolveRef(expanded); err != nil {
+			p.fail(envKey, fieldPath, redactString(expanded, fp.secret || rep.Resolved || rep.Decrypted), err)
+			return present
 		}
+		rep.Resolved = rep.Resolved || resolved
 	}
+	// Errors about secret values must not quote them, they end up in logs
+	sensitive := fp.secret || rep.Resolved || rep.Decrypted
 
-	return nil
+	// Check required
+	if envVal == "" && fp.required {
+		p.fail(envKey, fieldPath, "", ErrRequired)
+		return present
+	}
+
+	// Nothing to set, pointers stay nil without a value
+	if envVal == "" {
+		if fp.nonempty {
+			p.fail(envKey, fieldPath, "", fmt.Errorf("%w: must not be empty", ErrValidation))
+		}
+		return present
+	}
+
+	// Set value based on type
+	if err := setField(field, envVal, fp); err != nil {
+		if sensitive {
+			envVal, err = mask, fmt.Errorf("secret value is not a valid %v", field.Type())
+		}
+		p.fail(envKey, fieldPath, envVal, err)
+		return present
+	}
+
+	// Validation tags run against the parsed value
+	if err := p.validate(field, fp); err != nil {
+		if sensitive {
+			envVal, err = mask, fmt.Errorf("%w: secret value is invalid", ErrValidation)
+		}
+		p.fail(envKey, fieldPath, envVal, err)
+	}
+	return present
 }
 
-func setField(field reflect.Value, value string, fieldName string) error {
+// validate runs the validation tags of the field, if it has any.
+func (p *parser) validate(field reflect.Value, fp *fieldPlan) error {
+	if !fp.validates {
+		return nil
+	}
+	return validate(field, fp)
+}
+
+// isNestedStruct reports whether t is a struct walked field by field.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !isValueType(t)
+}
+
+func setField(field reflect.Value, value string, fp *fieldPlan) error {
+	// Registered decoders win over everything else
+	if ok, err := decodeRegistered(field, value); ok {
+		return err
+	}
+
+	// Pointers are allocated and the pointed-to value parsed as usual
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), value, fp); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	// Types with their own textual format, checked before the kind switch
+	// since time.Duration is an int64 underneath
+	switch field.Type() {
+	case durationType:
+		d, err := envyrt.Duration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := envyrt.Time(fp.layout)(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if ok, err := decodeText(field, value); ok {
+		return err
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		intValue, err := strconv.ParseInt(value, 10, 64)
+		intValue, err := envyrt.IntBits(value, field.Type().Bits())
 		if err != nil {
-			return fmt.Errorf("invalid int for field %s: %w", fieldName, err)
+			return err
 		}
 		field.SetInt(intValue)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		uintValue, err := strconv.ParseUint(value, 10, 64)
+		uintValue, err := envyrt.UintBits(value, field.Type().Bits())
 		if err != nil {
-			return fmt.Errorf("invalid uint for field %s: %w", fieldName, err)
+			return err
 		}
 		field.SetUint(uintValue)
 	case reflect.Bool:
-		boolValue, err := strconv.ParseBool(value)
+		boolValue, err := envyrt.Bool[bool](value)
 		if err != nil {
-			return fmt.Errorf("invalid bool for field %s: %w", fieldName, err)
+			return err
 		}
 		field.SetBool(boolValue)
 	case reflect.Float32, reflect.Float64:
-		floatValue, err := strconv.ParseFloat(value, 64)
+		floatValue, err := envyrt.Float[float64](value)
 		if err != nil {
-			return fmt.Errorf("invalid float for field %s: %w", fieldName, err)
+			return err
 		}
 		field.SetFloat(floatValue)
 	case reflect.Slice:
-		return setSlice(field, value, fieldName)
+		return setSlice(field, value, fp)
+	case reflect.Map:
+		return setMap(field, value, fp)
 	default:
-		return fmt.Errorf("unsupported type: %v for field %s", field.Kind(), fieldName)
+		return fmt.Errorf("%w: %v", ErrUnsupported, field.Type())
 	}
 	return nil
 }
 
-func setSlice(field reflect.Value, value string, fieldName string) error {
-	parts := strings.Split(value, ",")
-	// Trim spaces from each part
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+// isContainer reports whether elements of kind k can't be parsed from a
+// single list entry.
+func isContainer(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Map
+}
+
+// elemParser returns a parser of values of type t, sharing the scalar
+// parsing (and tags like layout) with fields.
+func elemParser(t reflect.Type, fp *fieldPlan) func(string) (reflect.Value, error) {
+	return func(s string) (reflect.Value, error) {
+		v := reflect.New(t).Elem()
+		return v, setField(v, s, fp)
+	}
+}
+
+func setSlice(field reflect.Value, value string, fp *fieldPlan) error {
+	elemType := field.Type().Elem()
+	if isContainer(elemType.Kind()) && !isValueType(elemType) {
+		return fmt.Errorf("%w: slice element %v", ErrUnsupported, elemType)
 	}
 
-	// Create a new slice with the correct length
-	slice := reflect.MakeSlice(field.Type(), 0, len(parts))
+	elems, err := envyrt.Slice(value, fp.sep, elemParser(elemType, fp))
+	if err != nil {
+		return err
+	}
 
-	for _, part := range parts {
-		if part == "" {
-			continue // Skip empty parts if desired, or handle them?
-			// For logic like "A,,B", splits to ["A", "", "B"].
-			// Usually valid elements are expected. Let's skip empty strings for now.
-		}
+	slice := reflect.MakeSlice(field.Type(), 0, len(elems))
+	slice = reflect.Append(slice, elems...)
+	field.Set(slice)
+	return nil
+}
 
-		elemVal := reflect.New(field.Type().Elem()).Elem()
+func setMap(field reflect.Value, value string, fp *fieldPlan) error {
+	keyType, elemType := field.Type().Key(), field.Type().Elem()
+	if isContainer(elemType.Kind()) && !isValueType(elemType) {
+		return fmt.Errorf("%w: map value %v", ErrUnsupported, elemType)
+	}
 
-		switch elemVal.Kind() {
-		case reflect.String:
-			elemVal.SetString(part)
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			val, err := strconv.ParseInt(part, 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid int in slice for field %s: %w", fieldName, err)
-			}
-			elemVal.SetInt(val)
-		case reflect.Float32, reflect.Float64:
-			val, err := strconv.ParseFloat(part, 64)
-			if err != nil {
-				return fmt.Errorf("invalid float in slice for field %s: %w", fieldName, err)
-			}
-			elemVal.SetFloat(val)
-		default:
-			return fmt.Errorf("unsupported slice element type: %v for field %s", elemVal.Kind(), fieldName)
-		}
-		slice = reflect.Append(slice, elemVal)
+	pairs, err := envyrt.Pairs(value, fp.sep, fp.kvSep, elemParser(keyType, fp), elemParser(elemType, fp))
+	if err != nil {
+		return err
 	}
 
-	field.Set(slice)
+	m := reflect.MakeMapWithSize(field.Type(), len(pairs))
+	for _, pair := range pairs {
+		m.SetMapIndex(pair.Key, pair.Value)
+	}
+	field.Set(m)
 	return nil
 }
@@ -1,9 +1,13 @@
 package envy
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type Config struct {
@@ -26,8 +30,7 @@ func TestLoad_NoEnvFile(t *testing.T) {
 	os.Remove(".env")
 
 	// Set required env var manually to avoid error
-	os.Setenv("API_KEY", "secret")
-	defer os.Unsetenv("API_KEY")
+	t.Setenv("API_KEY", "secret")
 
 	cfg := Config{}
 	err := Load(&cfg)
@@ -52,14 +55,16 @@ ALLOWED_HOSTS=example.com, api.example.com
 RETRY_BACKOFF=10, 20, 30
 TEMPERATURES=23.5, 98.6, 100.0`
 
-	err := os.WriteFile(".env", []byte(envContent), 0644)
+	// Written outside the package and kept in an overlay, so nothing leaks
+	// into the process environment of other tests
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".env"), []byte(envContent), 0644)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(".env")
 
 	cfg := Config{}
-	err = Load(&cfg)
+	err = Load(&cfg, WithEnvDir(dir), WithOverlay())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -96,7 +101,7 @@ TEMPERATURES=23.5, 98.6, 100.0`
 
 func TestLoad_RequiredMissing(t *testing.T) {
 	os.Remove(".env")
-	os.Unsetenv("API_KEY")
+	t.Setenv("API_KEY", "") // Empty counts as not set
 
 	cfg := Config{}
 	err := Load(&cfg)
@@ -104,3 +109,203 @@ func TestLoad_RequiredMissing(t *testing.T) {
 		t.Fatal("expected error due to missing required field, got nil")
 	}
 }
+
+func TestLoad_DurationAndTime(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("HTTP_TIMEOUT", "30s")
+	t.Setenv("BACKOFF", "100ms, 1s, 1m")
+	t.Setenv("RELEASED_AT", "2024-02-01T10:00:00Z")
+	t.Setenv("MAINTENANCE_DAYS", "2024-03-01, 2024-03-15")
+
+	var cfg struct {
+		Timeout     time.Duration   `env:"HTTP_TIMEOUT"`
+		IdleTimeout time.Duration   `env:"IDLE_TIMEOUT" default:"5m"`
+		Backoff     []time.Duration `env:"BACKOFF"`
+		ReleasedAt  time.Time       `env:"RELEASED_AT"`
+		Maintenance []time.Time     `env:"MAINTENANCE_DAYS" layout:"2006-01-02"`
+	}
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Timeout != 30*time.Second {
+		t.Errorf("expected timeout 30s, got %v", cfg.Timeout)
+	}
+	if cfg.IdleTimeout != 5*time.Minute {
+		t.Errorf("expected default idle timeout 5m, got %v", cfg.IdleTimeout)
+	}
+	expectedBackoff := []time.Duration{100 * time.Millisecond, time.Second, time.Minute}
+	if !reflect.DeepEqual(cfg.Backoff, expectedBackoff) {
+		t.Errorf("expected backoff %v, got %v", expectedBackoff, cfg.Backoff)
+	}
+	if !cfg.ReleasedAt.Equal(time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected released at %v", cfg.ReleasedAt)
+	}
+	if len(cfg.Maintenance) != 2 || !cfg.Maintenance[1].Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected maintenance days %v", cfg.Maintenance)
+	}
+}
+
+func TestLoad_InvalidDuration(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("HTTP_TIMEOUT", "30")
+
+	var cfg struct {
+		Timeout time.Duration `env:"HTTP_TIMEOUT"`
+	}
+	if err := Load(&cfg); err == nil {
+		t.Fatal("expected error for duration without unit, got nil")
+	}
+}
+
+func TestLoad_Maps(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("TENANT_LIMITS", "acme:100, globex:250")
+	t.Setenv("LABELS", "team=core;tier=backend")
+	t.Setenv("TIMEOUTS", "read:5s,write:10s")
+
+	var cfg struct {
+		Limits   map[string]int           `env:"TENANT_LIMITS"`
+		Labels   map[string]string        `env:"LABELS" sep:";" kvsep:"="`
+		Timeouts map[string]time.Duration `env:"TIMEOUTS"`
+		Weights  map[string]float64       `env:"WEIGHTS" default:"a:0.5"`
+	}
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.Limits, map[string]int{"acme": 100, "globex": 250}) {
+		t.Errorf("unexpected limits %v", cfg.Limits)
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "core", "tier": "backend"}) {
+		t.Errorf("unexpected labels %v", cfg.Labels)
+	}
+	if !reflect.DeepEqual(cfg.Timeouts, map[string]time.Duration{"read": 5 * time.Second, "write": 10 * time.Second}) {
+		t.Errorf("unexpected timeouts %v", cfg.Timeouts)
+	}
+	if !reflect.DeepEqual(cfg.Weights, map[string]float64{"a": 0.5}) {
+		t.Errorf("unexpected weights %v", cfg.Weights)
+	}
+}
+
+func TestLoad_MapMalformed(t *testing.T) {
+	os.Remove(".env")
+
+	var cfg struct {
+		Limits map[string]int `env:"TENANT_LIMITS"`
+	}
+	for _, value := range []string{"acme", ":100", "acme:lots"} {
+		t.Setenv("TENANT_LIMITS", value)
+		err := Load(&cfg)
+		if err == nil {
+			t.Fatalf("expected error for %q, got nil", value)
+		}
+		if !strings.Contains(err.Error(), "Limits") {
+			t.Errorf("expected error to name the field, got %v", err)
+		}
+	}
+}
+
+func TestLoad_Pointers(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("RETRIES", "0")
+	t.Setenv("VERBOSE", "")
+	t.Setenv("REPLICA_DSN", "postgres://replica")
+
+	type database struct {
+		DSN  string `env:"REPLICA_DSN"`
+		Pool int    `env:"REPLICA_POOL" default:"5"`
+	}
+	var cfg struct {
+		Retries   *int           `env:"RETRIES"`
+		Verbose   *bool          `env:"VERBOSE"`
+		Name      *string        `env:"SERVICE_NAME"`
+		Timeout   *time.Duration `env:"TIMEOUT" default:"10s"`
+		Replica   *database
+		Secondary *struct {
+			DSN string `env:"SECONDARY_DSN" required:"true"`
+		}
+	}
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Retries == nil || *cfg.Retries != 0 {
+		t.Errorf("expected retries to point to 0, got %v", cfg.Retries)
+	}
+	if cfg.Verbose == nil || *cfg.Verbose {
+		t.Errorf("expected explicitly empty verbose to point to false, got %v", cfg.Verbose)
+	}
+	if cfg.Name != nil {
+		t.Errorf("expected unset name to stay nil, got %q", *cfg.Name)
+	}
+	if cfg.Timeout == nil || *cfg.Timeout != 10*time.Second {
+		t.Errorf("expected default timeout 10s, got %v", cfg.Timeout)
+	}
+	if cfg.Replica == nil || cfg.Replica.DSN != "postgres://replica" || cfg.Replica.Pool != 5 {
+		t.Errorf("expected replica to be allocated with defaults, got %+v", cfg.Replica)
+	}
+	if cfg.Secondary != nil {
+		t.Errorf("expected unset secondary to stay nil, got %+v", cfg.Secondary)
+	}
+}
+
+func TestLoad_RequiredPointer(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("REQ_NAME", "")
+
+	var cfg struct {
+		Name *string `env:"REQ_NAME" required:"true"`
+	}
+	err := Load(&cfg)
+	if !errors.Is(err, ErrRequired) || !strings.Contains(err.Error(), "REQ_NAME") {
+		t.Errorf("expected ErrRequired for an empty required pointer, got %v", err)
+	}
+	if cfg.Name != nil {
+		t.Errorf("expected name to stay nil, got %q", *cfg.Name)
+	}
+}
+
+func TestLoad_EnvPrefix(t *testing.T) {
+	os.Remove(".env")
+	t.Setenv("DB_DSN", "postgres://primary")
+	t.Setenv("REPLICA_DB_DSN", "postgres://replica")
+	t.Setenv("REPLICA_DB_POOL", "3")
+	t.Setenv("EU_CACHE_REDIS_ADDR", "redis-eu:6379")
+
+	type database struct {
+		DSN  string `env:"DB_DSN"`
+		Pool int    `env:"DB_POOL" default:"10"`
+	}
+	type cache struct {
+		Redis struct {
+			Addr string `env:"REDIS_ADDR" default:"localhost:6379"`
+		} `envPrefix:"CACHE_"`
+	}
+	var cfg struct {
+		Primary database
+		Replica database  `envPrefix:"REPLICA_"`
+		Backup  *database `envPrefix:"BACKUP_"`
+		EU      cache     `envPrefix:"EU_"`
+		US      cache     `envPrefix:"US_"`
+	}
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Primary.DSN != "postgres://primary" || cfg.Primary.Pool != 10 {
+		t.Errorf("unexpected primary %+v", cfg.Primary)
+	}
+	if cfg.Replica.DSN != "postgres://replica" || cfg.Replica.Pool != 3 {
+		t.Errorf("unexpected replica %+v", cfg.Replica)
+	}
+	if cfg.Backup != nil {
+		t.Errorf("expected backup to stay nil, got %+v", cfg.Backup)
+	}
+	if cfg.EU.Redis.Addr != "redis-eu:6379" {
+		t.Errorf("expected eu redis addr from EU_CACHE_REDIS_ADDR, got %s", cfg.EU.Redis.Addr)
+	}
+	if cfg.US.Redis.Addr != "localhost:6379" {
+		t.Errorf("expected us redis addr default, got %s", cfg.US.Redis.Addr)
+	}
+}
@@ -0,0 +1,79 @@
+// Package envyrt holds the parts of envy that don't need reflection: the
+// errors, value parsing and interpolation. It is shared by the envy package
+// and the code generated by envygen, so both behave the same.
+package envyrt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors matched with errors.Is against the error returned by Load.
+var (
+	ErrRequired    = errors.New("required but not set")
+	ErrParse       = errors.New("parse error")
+	ErrUnsupported = errors.New("unsupported type")
+	ErrValidation  = errors.New("validation failed")
+	ErrUnknown     = errors.New("unknown variable")
+)
+
+// FieldError describes a problem with a single field.
+type FieldError struct {
+	Key   string // Env key, including any envPrefix
+	Field string // Struct field path, e.g. "Database.DSN", empty for unknown keys
+	Value string // Raw value that was being parsed, if any
+	Err   error  // Cause, wraps one of the sentinel errors
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("var `%s`: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("var `%s` (%s): %v", e.Key, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// NewFieldError returns the error of a field, classifying errors that
+// don't already wrap one of the sentinels as parse errors.
+func NewFieldError(key, path, value string, err error) *FieldError {
+	classified := false
+	for _, sentinel := range []error{ErrRequired, ErrUnsupported, ErrValidation, ErrUnknown} {
+		classified = classified || errors.Is(err, sentinel)
+	}
+	if !classified {
+		err = fmt.Errorf("%w: %w", ErrParse, err)
+	}
+	return &FieldError{Key: key, Field: path, Value: value, Err: err}
+}
+
+// Errors collects every problem found while loading a struct, so a broken
+// deploy reports everything at once instead of one error per restart.
+type Errors struct {
+	Errs []*FieldError
+}
+
+func (e *Errors) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d config errors:", len(e.Errs))
+	for _, err := range e.Errs {
+		b.WriteString("\n  - ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *Errors) Unwrap() []error {
+	errs := make([]error, len(e.Errs))
+	for i, err := range e.Errs {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,178 @@
+package envyrt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expander resolves ${VAR} references in values and defaults, and $VAR
+// in values read from .env files. What a reference expands to is up to
+// the caller, usually the environment first, then the `default` tags of
+// the target struct, so one field's default can build on another's.
+type Expander struct {
+	value     func(key string) (string, error)
+	resolving []string // Keys being expanded, to detect reference cycles
+}
+
+// NewExpander returns an Expander evaluating referenced keys with value,
+// which expands the value it finds with Expand or ExpandDotenv in turn.
+func NewExpander(value func(key string) (string, error)) *Expander {
+	return &Expander{value: value}
+}
+
+// Expand replaces the ${VAR} references in s, the value of key. A $ not
+// followed by { or another $ is kept as is.
+func (e *Expander) Expand(key, s string) (string, error) {
+	return e.expand(key, s, false)
+}
+
+// ExpandDotenv is like Expand for values read from .env files, where $VAR
+// is a reference too, as godotenv expanded it.
+func (e *Expander) ExpandDotenv(key, s string) (string, error) {
+	return e.expand(key, s, true)
+}
+
+func (e *Expander) expand(key, s string, bare bool) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	e.resolving = append(e.resolving, key)
+	defer func() { e.resolving = e.resolving[:len(e.resolving)-1] }()
+
+	return e.expandRefs(s, bare)
+}
+
+// expandRefs replaces the references in s on behalf of the key currently
+// being expanded. Errors name that key rather than quoting s, which may be
+// a secret. bare tells whether $VAR is a reference.
+func (e *Expander) expandRefs(s string, bare bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$') // $$ escapes a literal dollar
+			i++
+		case '{':
+			end := matchingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in %s", e.current())
+			}
+			value, err := e.reference(s[i+2:end], bare)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		default:
+			// $NAME, as in shells and godotenv, a lone $ is kept as is
+			if !bare {
+				b.WriteByte('$')
+				continue
+			}
+			end := i + 1
+			for end < len(s) && isNameByte(s[end], end > i+1) {
+				end++
+			}
+			if end == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			value, err := e.reference(s[i+1:end], bare)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end - 1
+		}
+	}
+	return b.String(), nil
+}
+
+// reference evaluates the inside of ${...}: NAME, NAME:-fallback or
+// NAME:?message.
+func (e *Expander) reference(ref string, bare bool) (string, error) {
+	name, op, arg := ref, "", ""
+	if i := strings.Index(ref, ":"); i >= 0 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	if !validName(name) {
+		return "", fmt.Errorf("invalid reference name in %s", e.current())
+	}
+
+	value, err := e.resolve(name)
+	if err != nil || value != "" {
+		return value, err
+	}
+
+	switch op {
+	case ":-":
+		return e.expandRefs(arg, bare)
+	case ":?":
+		if arg == "" {
+			arg = "not set"
+		}
+		return "", fmt.Errorf("%s: %s", name, arg)
+	}
+	return "", nil
+}
+
+// resolve returns the expanded value of key, from the environment or the
+// field default, mirroring how fields fall back on empty values. Keys
+// already being expanded are a cycle.
+func (e *Expander) resolve(key string) (string, error) {
+	for i, k := range e.resolving {
+		if k == key {
+			cycle := append(append([]string{}, e.resolving[i:]...), key)
+			return "", fmt.Errorf("reference cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	return e.value(key)
+}
+
+// current returns the key being expanded.
+func (e *Expander) current() string {
+	return e.resolving[len(e.resolving)-1]
+}
+
+// matchingBrace returns the index of the } closing a reference whose body
+// starts at start, allowing nested references in fallbacks.
+func matchingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameByte(name[i], i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNameByte reports whether c can appear in a variable name, digits only
+// after the first byte.
+func isNameByte(c byte, inside bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || inside && c >= '0' && c <= '9'
+}
@@ -0,0 +1,84 @@
+package envyrt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Loader runs the per-field steps of envy for generated code: lookup,
+// default, interpolation and the required check, collecting errors the
+// same way.
+type Loader struct {
+	lookup   func(string) (string, bool)
+	expander *Expander
+	errs     []*FieldError
+}
+
+// NewLoader returns a Loader reading values with lookup, os.LookupEnv for
+// the process environment. defaults holds the `default` tags by env key,
+// used when interpolating.
+func NewLoader(lookup func(string) (string, bool), defaults map[string]string) *Loader {
+	l := &Loader{lookup: lookup}
+	l.expander = NewExpander(func(key string) (string, error) {
+		value, _ := lookup(key)
+		if value == "" {
+			value = defaults[key]
+		}
+		expanded, err := l.expander.Expand(key, value)
+		if err == nil && isSecretValue(expanded) {
+			err = fmt.Errorf("%w: %s: secret references and encrypted values need envy", ErrUnsupported, key)
+		}
+		return expanded, err
+	})
+	return l
+}
+
+// Value returns the value of a field to parse: the env var, else its
+// default, with references expanded. It reports false when there is
+// nothing to set, recording an error if the field is required or the
+// value can't be expanded. Unlike envy, a required field falling back to
+// its default isn't logged, generated code has no logger to write to.
+//
+// envy resolves ref+ secret references and decrypts ENC[...] values,
+// generated code can't, so they fail with ErrUnsupported rather than
+// ending up in the config as is.
+func (l *Loader) Value(key, path, def string, required bool) (string, bool) {
+	value, _ := l.lookup(key)
+	if value == "" && def != "" {
+		value = def
+	}
+
+	expanded, err := l.expander.Expand(key, value)
+	if err != nil {
+		l.Fail(key, path, value, err)
+		return "", false
+	}
+	if isSecretValue(expanded) {
+		l.Fail(key, path, "", fmt.Errorf("%w: secret references and encrypted values need envy", ErrUnsupported))
+		return "", false
+	}
+	if expanded == "" && required {
+		l.Fail(key, path, "", ErrRequired)
+	}
+	return expanded, expanded != ""
+}
+
+// Fail records the error of a field, see NewFieldError.
+func (l *Loader) Fail(key, path, value string, err error) {
+	l.errs = append(l.errs, NewFieldError(key, path, value, err))
+}
+
+// Err returns the errors recorded so far as an *Errors, or nil.
+func (l *Loader) Err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return &Errors{Errs: l.errs}
+}
+
+// isSecretValue reports whether envy would resolve or decrypt value, it
+// follows the prefixes of envy's resolveRef and isEncrypted.
+func isSecretValue(value string) bool {
+	return strings.HasPrefix(value, "ref+") ||
+		strings.HasPrefix(value, "ENC[AES256_GCM,") && strings.HasSuffix(value, "]")
+}
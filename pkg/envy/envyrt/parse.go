@@ -0,0 +1,180 @@
+package envyrt
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// String returns s as is, for use with Slice and Map.
+func String[T ~string](s string) (T, error) {
+	return T(s), nil
+}
+
+// Int parses a base 10 integer, values out of the range of T are an error.
+func Int[T ~int | ~int8 | ~int16 | ~int32 | ~int64](s string) (T, error) {
+	n, err := IntBits(s, int(unsafe.Sizeof(T(0)))*8)
+	return T(n), err
+}
+
+// IntBits parses a base 10 integer fitting in bits, for callers knowing
+// the size of the target at run time only.
+func IntBits(s string, bits int) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid int: %w", err)
+	}
+	return n, nil
+}
+
+// Uint parses a base 10 unsigned integer, see Int.
+func Uint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr](s string) (T, error) {
+	n, err := UintBits(s, int(unsafe.Sizeof(T(0)))*8)
+	return T(n), err
+}
+
+// UintBits parses a base 10 unsigned integer fitting in bits, see IntBits.
+func UintBits(s string, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uint: %w", err)
+	}
+	return n, nil
+}
+
+// Float parses a floating point number.
+func Float[T ~float32 | ~float64](s string) (T, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float: %w", err)
+	}
+	return T(f), nil
+}
+
+// Bool parses the values accepted by strconv.ParseBool.
+func Bool[T ~bool](s string) (T, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid bool: %w", err)
+	}
+	return T(b), nil
+}
+
+// Duration parses a time.ParseDuration string such as "1m30s".
+func Duration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %w", err)
+	}
+	return d, nil
+}
+
+// Time returns a parser of times in layout, the `layout` tag, RFC3339 when
+// empty.
+func Time(layout string) func(string) (time.Time, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return func(s string) (time.Time, error) {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time: %w", err)
+		}
+		return t, nil
+	}
+}
+
+// Text parses s with the UnmarshalText method of *T.
+func Text[T any, PT interface {
+	*T
+	encoding.TextUnmarshaler
+}](s string) (T, error) {
+	var v T
+	if err := PT(&v).UnmarshalText([]byte(s)); err != nil {
+		return v, fmt.Errorf("invalid %T: %w", v, err)
+	}
+	return v, nil
+}
+
+// Separators returns the element separator (`sep` tag, "," by default) and
+// the key/value separator for maps (`kvsep` tag, ":" by default).
+func Separators(sep, kvSep string) (string, string) {
+	if sep == "" {
+		sep = ","
+	}
+	if kvSep == "" {
+		kvSep = ":"
+	}
+	return sep, kvSep
+}
+
+// Slice splits value on sep and parses each element, skipping empty ones:
+// "A,,B" holds two elements.
+func Slice[T any](value, sep string, parse func(string) (T, error)) ([]T, error) {
+	parts := strings.Split(value, sep)
+	slice := make([]T, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		elem, err := parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid element in slice: %w", err)
+		}
+		slice = append(slice, elem)
+	}
+	return slice, nil
+}
+
+// Pair is a map entry parsed by Pairs.
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Pairs splits value on sep into key/value entries separated by kvSep and
+// parses them, in order. Empty entries are skipped, like with Slice.
+func Pairs[K, V any](value, sep, kvSep string, parseKey func(string) (K, error), parseValue func(string) (V, error)) ([]Pair[K, V], error) {
+	var pairs []Pair[K, V]
+	for _, entry := range strings.Split(value, sep) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(entry, kvSep)
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid entry %q in map: expected key%svalue", entry, kvSep)
+		}
+
+		key, err := parseKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in map: %w", err)
+		}
+		val, err := parseValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for key %q in map: %w", k, err)
+		}
+		pairs = append(pairs, Pair[K, V]{key, val})
+	}
+	return pairs, nil
+}
+
+// Map parses value with Pairs, later entries win over earlier ones with the
+// same key.
+func Map[K comparable, V any](value, sep, kvSep string, parseKey func(string) (K, error), parseValue func(string) (V, error)) (map[K]V, error) {
+	pairs, err := Pairs(value, sep, kvSep, parseKey, parseValue)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[K]V, len(pairs))
+	for _, p := range pairs {
+		m[p.Key] = p.Value
+	}
+	return m, nil
+}
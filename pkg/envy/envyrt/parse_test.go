@@ -0,0 +1,59 @@
+package envyrt
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSliceAndMap(t *testing.T) {
+	s, err := Slice(" 1, 2,,3 ", ",", Int[int])
+	if err != nil || !reflect.DeepEqual(s, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v, %v", s, err)
+	}
+	if _, err := Slice("1,x", ",", Int[int]); !errors.Is(err, strconv.ErrSyntax) || err.Error() != `invalid element in slice: invalid int: strconv.ParseInt: parsing "x": invalid syntax` {
+		t.Errorf("expected an invalid element error, got %v", err)
+	}
+
+	m, err := Map("a=1;b=2;a=3", ";", "=", String[string], Uint[uint16])
+	if err != nil || !reflect.DeepEqual(m, map[string]uint16{"a": 3, "b": 2}) {
+		t.Errorf("expected map[a:3 b:2], got %v, %v", m, err)
+	}
+	if _, err := Map("a", ",", ":", String[string], String[string]); err == nil || err.Error() != `invalid entry "a" in map: expected key:value` {
+		t.Errorf("expected an invalid entry error, got %v", err)
+	}
+}
+
+func TestIntRange(t *testing.T) {
+	if n, err := Uint[uint8]("255"); err != nil || n != 255 {
+		t.Errorf("expected 255, got %v, %v", n, err)
+	}
+	if _, err := Uint[uint8]("300"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected a range error, got %v", err)
+	}
+	if _, err := Int[int16]("-40000"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected a range error, got %v", err)
+	}
+}
+
+func TestText(t *testing.T) {
+	ip, err := Text[net.IP]("10.0.0.1")
+	if err != nil || !ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("expected 10.0.0.1, got %v, %v", ip, err)
+	}
+	if _, err := Text[net.IP]("10.0.0"); err == nil || err.Error() != "invalid net.IP: invalid IP address: 10.0.0" {
+		t.Errorf("expected an invalid net.IP error, got %v", err)
+	}
+}
+
+func TestNewFieldError(t *testing.T) {
+	err := NewFieldError("PORT", "Port", "x", errors.New("bad"))
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("expected ErrParse, got %v", err)
+	}
+	if err := NewFieldError("PORT", "Port", "", ErrRequired); errors.Is(err, ErrParse) {
+		t.Errorf("expected ErrRequired only, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package envytest loads envy configs in tests without touching the process
+// environment or the working directory, so tests stay hermetic and can run
+// with t.Parallel().
+//
+//	cfg, err := envytest.Load[Config](t, map[string]string{"PORT": "9090"})
+//	envytest.ExpectNoError(t, err)
+//	envytest.ExpectField(t, cfg, "Port", 9090)
+package envytest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/moeghifar/libgo/pkg/envy"
+)
+
+// Load loads a T reading only env, as if it were the whole environment.
+// Warnings are logged with t.Log.
+func Load[T any](t testing.TB, env map[string]string, opts ...envy.Option) (T, error) {
+	t.Helper()
+	return envy.Parse[T](hermetic(t, opts, envy.MapSource(env))...)
+}
+
+// LoadDotenv loads a T reading only dotenv, the contents of a .env file.
+// A syntax error in dotenv fails the test.
+func LoadDotenv[T any](t testing.TB, dotenv string, opts ...envy.Option) (T, error) {
+	t.Helper()
+	return envy.Parse[T](hermetic(t, opts, dotenvSource(t, dotenv))...)
+}
+
+// hermetic sets explicit sources, so envy reads neither the process
+// environment nor .env files, and routes warnings to the test log. Sources
+// passed in opts are read after src.
+func hermetic(t testing.TB, opts []envy.Option, src ...envy.Source) []envy.Option {
+	logger := slog.New(slog.NewTextHandler(testWriter{t}, nil))
+	return append([]envy.Option{envy.WithLogger(logger), envy.WithSources(src...)}, opts...)
+}
+
+func dotenvSource(t testing.TB, dotenv string) envy.Source {
+	t.Helper()
+	src, err := envy.FSSource(fstest.MapFS{".env": {Data: []byte(dotenv)}}, ".env")
+	if err != nil {
+		t.Fatalf("envytest: invalid dotenv: %v", err)
+	}
+	return src
+}
+
+type testWriter struct{ t testing.TB }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// ExpectNoError fails the test if loading failed, listing every error.
+func ExpectNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("envytest: unexpected error: %v", err)
+	}
+}
+
+// ExpectError fails the test unless err holds an error for the env key
+// matching target with errors.Is. A nil target matches any error.
+func ExpectError(t testing.TB, err error, key string, target error) {
+	t.Helper()
+	fe := fieldError(err, key)
+	switch {
+	case fe == nil:
+		t.Errorf("envytest: expected an error for %s, got: %v", key, err)
+	case target != nil && !errors.Is(fe, target):
+		t.Errorf("envytest: expected %v for %s, got %v", target, key, fe)
+	}
+}
+
+func fieldError(err error, key string) *envy.FieldError {
+	var errs *envy.Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	for _, fe := range errs.Errs {
+		if fe.Key == key {
+			return fe
+		}
+	}
+	return nil
+}
+
+// ExpectField fails the test unless the field at path of cfg, e.g.
+// "Database.DSN", deeply equals want. Pointers are followed, so want can
+// be the pointed-to value, or nil for a nil pointer.
+func ExpectField(t testing.TB, cfg any, path string, want any) {
+	t.Helper()
+	got, err := field(cfg, path)
+	if err != nil {
+		t.Errorf("envytest: %v", err)
+		return
+	}
+
+	if want == nil {
+		if got.Kind() != reflect.Ptr || !got.IsNil() {
+			t.Errorf("envytest: expected %s to be nil, got %v", path, got)
+		}
+		return
+	}
+	for got.Kind() == reflect.Ptr && got.Type() != reflect.TypeOf(want) {
+		if got.IsNil() {
+			t.Errorf("envytest: expected %s = %#v, got nil", path, want)
+			return
+		}
+		got = got.Elem()
+	}
+	if !reflect.DeepEqual(got.Interface(), want) {
+		t.Errorf("envytest: expected %s = %#v, got %#v", path, want, got.Interface())
+	}
+}
+
+// field returns the field at path of cfg, a struct or a pointer to one.
+func field(cfg any, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg)
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("%s: nil pointer before %s", path, name)
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("%s: %s is not a struct", path, v.Type())
+		}
+		v = v.FieldByName(name)
+		if !v.IsValid() {
+			return reflect.Value{}, fmt.Errorf("%s: no field %s", path, name)
+		}
+	}
+	return v, nil
+}
+
+// Case is a table entry for Run. Env takes precedence over Dotenv, like
+// the process environment over a .env file.
+type Case struct {
+	Name   string
+	Env    map[string]string
+	Dotenv string
+	Fields map[string]any   // Expected values by field path
+	Errors map[string]error // Expected errors by env key, nil matches any error
+}
+
+// Run loads a T for each case in a parallel subtest and checks the fields
+// and errors. Loading must succeed when a case expects no errors, and
+// fail with exactly the expected keys otherwise.
+func Run[T any](t *testing.T, cases []Case, opts ...envy.Option) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+
+			src := []envy.Source{envy.MapSource(c.Env)}
+			if c.Dotenv != "" {
+				src = append(src, dotenvSource(t, c.Dotenv))
+			}
+			cfg, err := envy.Parse[T](hermetic(t, opts, src...)...)
+
+			if len(c.Errors) == 0 {
+				ExpectNoError(t, err)
+			}
+			for key, target := range c.Errors {
+				ExpectError(t, err, key, target)
+			}
+			var errs *envy.Errors
+			if errors.As(err, &errs) {
+				for _, fe := range errs.Errs {
+					if _, ok := c.Errors[fe.Key]; !ok && len(c.Errors) > 0 {
+						t.Errorf("envytest: unexpected error: %v", fe)
+					}
+				}
+			}
+
+			for path, want := range c.Fields {
+				ExpectField(t, &cfg, path, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package envytest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/moeghifar/libgo/pkg/envy"
+)
+
+type config struct {
+	Port    int           `env:"PORT" default:"8080" min:"1"`
+	Host    string        `env:"HOST" required:"true"`
+	Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+	Tags    []string      `env:"TAGS"`
+	DB      *struct {
+		DSN string `env:"DSN" required:"true"`
+	} `envPrefix:"DB_"`
+}
+
+func TestRun(t *testing.T) {
+	// Other tests set the same keys in the process environment, which
+	// must not leak into the cases
+	t.Setenv("HOST", "from-process")
+
+	Run[config](t, []Case{
+		{
+			Name:   "defaults",
+			Env:    map[string]string{"HOST": "localhost"},
+			Fields: map[string]any{"Port": 8080, "Host": "localhost", "Timeout": 5 * time.Second, "Tags": []string(nil), "DB": nil},
+		},
+		{
+			Name:   "dotenv",
+			Dotenv: "HOST=db.internal\nTAGS=a,b\nDB_DSN=postgres://db\n",
+			Env:    map[string]string{"PORT": "9090"},
+			Fields: map[string]any{"Port": 9090, "Host": "db.internal", "Tags": []string{"a", "b"}, "DB.DSN": "postgres://db"},
+		},
+		{
+			Name:   "env wins over dotenv",
+			Dotenv: "HOST=file",
+			Env:    map[string]string{"HOST": "env"},
+			Fields: map[string]any{"Host": "env"},
+		},
+		{
+			Name:   "errors",
+			Env:    map[string]string{"PORT": "0", "TIMEOUT": "soon", "DB_DSN": ""},
+			Errors: map[string]error{"HOST": envy.ErrRequired, "PORT": envy.ErrValidation, "TIMEOUT": envy.ErrParse, "DB_DSN": nil},
+		},
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load[config](t, map[string]string{"HOST": "h", "DB_DSN": "x"})
+	ExpectNoError(t, err)
+	ExpectField(t, cfg, "Host", "h")
+	ExpectField(t, &cfg, "DB.DSN", "x")
+
+	cfg, err = LoadDotenv[config](t, "# comment\nPORT=abc\n")
+	ExpectError(t, err, "HOST", envy.ErrRequired)
+	ExpectError(t, err, "PORT", envy.ErrParse)
+	ExpectField(t, cfg, "Port", 0)
+}
+
+// recorder is a testing.TB recording failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(format string, a ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, a...))
+}
+
+func TestExpect_Failures(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load[config](t, map[string]string{"PORT": "x", "HOST": "h"})
+	r := &recorder{TB: t}
+	ExpectNoError(r, err)
+	ExpectError(r, err, "PORT", envy.ErrRequired)
+	ExpectError(r, err, "HOST", nil)
+	ExpectField(r, cfg, "Host", "other")
+	ExpectField(r, cfg, "Host", nil)
+	ExpectField(r, cfg, "DB.DSN", "x")
+	ExpectField(r, cfg, "Missing", 1)
+	ExpectField(r, cfg, "Port", 0) // Passes
+
+	want := []string{
+		"envytest: unexpected error: var `PORT` (Port): parse error: invalid int: strconv.ParseInt: parsing \"x\": invalid syntax",
+		"envytest: expected required but not set for PORT, got var `PORT` (Port): parse error: invalid int: strconv.ParseInt: parsing \"x\": invalid syntax",
+		"envytest: expected an error for HOST, got: var `PORT` (Port): parse error: invalid int: strconv.ParseInt: parsing \"x\": invalid syntax",
+		`envytest: expected Host = "other", got "h"`,
+		"envytest: expected Host to be nil, got h",
+		"envytest: DB.DSN: nil pointer before DSN",
+		"envytest: Missing: no field Missing",
+	}
+	if len(r.errs) != len(want) {
+		t.Fatalf("expected %d failures, got %d:\n%q", len(want), len(r.errs), r.errs)
+	}
+	for i := range want {
+		if r.errs[i] != want[i] {
+			t.Errorf("expected failure %d to be %q, got %q", i, want[i], r.errs[i])
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package envy
+
+import "github.com/moeghifar/libgo/pkg/envy/envyrt"
+
+// Sentinel errors matched with errors.Is against the error returned by Load.
+var (
+	ErrRequired    = envyrt.ErrRequired
+	ErrParse       = envyrt.ErrParse
+	ErrUnsupported = envyrt.ErrUnsupported
+	ErrValidation  = envyrt.ErrValidation // A value fails one of the validation tags
+	ErrUnknown     = envyrt.ErrUnknown    // Returned in strict mode, see WithStrict
+)
+
+// FieldError describes a problem with a single field.
+type FieldError = envyrt.FieldError
+
+// Errors collects every problem found while loading a struct, so a broken
+// deploy reports everything at once instead of one error per restart.
+type Errors = envyrt.Errors
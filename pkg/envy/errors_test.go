@@ -0,0 +1,66 @@
+package envy
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoad_AggregatesErrors(t *testing.T) {
+	t.Setenv("DB_POOL", "lots")
+	t.Setenv("RETRY_BACKOFF", "1,x")
+	t.Setenv("EVENTS", "ignored")
+
+	var cfg struct {
+		APIKey   string `env:"API_KEY" required:"true"`
+		Database struct {
+			URL  string `env:"URL" required:"true"`
+			Pool int    `env:"POOL"`
+		} `envPrefix:"DB_"`
+		Backoff []int         `env:"RETRY_BACKOFF"`
+		Events  chan struct{} `env:"EVENTS"`
+	}
+	err := parse(&cfg)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected *Errors, got %T", err)
+	}
+	if len(errs.Errs) != 5 {
+		t.Fatalf("expected 5 errors, got %d: %v", len(errs.Errs), err)
+	}
+
+	expected := []struct {
+		key, field, value string
+		kind              error
+	}{
+		{"API_KEY", "APIKey", "", ErrRequired},
+		{"DB_URL", "Database.URL", "", ErrRequired},
+		{"DB_POOL", "Database.Pool", "lots", ErrParse},
+		{"RETRY_BACKOFF", "Backoff", "1,x", ErrParse},
+		{"EVENTS", "Events", "ignored", ErrUnsupported},
+	}
+	for i, want := range expected {
+		got := errs.Errs[i]
+		if got.Key != want.key || got.Field != want.field || got.Value != want.value {
+			t.Errorf("error %d: expected %s/%s/%q, got %s/%s/%q", i, want.key, want.field, want.value, got.Key, got.Field, got.Value)
+		}
+		if !errors.Is(got, want.kind) {
+			t.Errorf("error %d: expected %v, got %v", i, want.kind, got.Err)
+		}
+	}
+
+	if !errors.Is(err, ErrRequired) || !errors.Is(err, ErrParse) || !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected aggregate to match every sentinel, got %v", err)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("expected aggregate to expose the underlying cause, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "5 config errors:") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
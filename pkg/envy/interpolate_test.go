@@ -0,0 +1,103 @@
+package envy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Interpolation(t *testing.T) {
+	t.Setenv("I_USER", "app")
+	t.Setenv("I_PASS", "p$$w")
+	t.Setenv("I_DSN", "postgres://${I_USER}:${I_PASS}@${I_HOST}/app")
+	t.Setenv("I_HOME", "/home/app")
+	t.Setenv("I_PRICE", "$$5")
+	t.Setenv("I_URL", "http://$I_USER@host/$")
+
+	var cfg struct {
+		DSN      string `env:"I_DSN"`
+		Host     string `env:"I_HOST" default:"db:5432"`
+		CacheDir string `env:"I_CACHE_DIR" default:"${I_HOME}/.cache/app"`
+		Region   string `env:"I_REGION" default:"${I_ZONE:-eu}-west"`
+		Price    string `env:"I_PRICE"`
+		URL      string `env:"I_URL"`
+		Nested   struct {
+			URL  string `env:"URL" default:"http://${I_HOST}/${I_NESTED_PATH}"`
+			Path string `env:"PATH" default:"v1"`
+		} `envPrefix:"I_NESTED_"`
+	}
+	if err := parse(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.DSN != "postgres://app:p$w@db:5432/app" {
+		t.Errorf("unexpected dsn %q", cfg.DSN)
+	}
+	if cfg.CacheDir != "/home/app/.cache/app" {
+		t.Errorf("unexpected cache dir %q", cfg.CacheDir)
+	}
+	if cfg.Region != "eu-west" {
+		t.Errorf("unexpected region %q", cfg.Region)
+	}
+	if cfg.Price != "$5" {
+		t.Errorf("expected $$ to escape, got %q", cfg.Price)
+	}
+	if cfg.URL != "http://$I_USER@host/$" {
+		t.Errorf("expected $I_USER outside .env files and a lone $ to be kept, got %q", cfg.URL)
+	}
+	if cfg.Nested.URL != "http://db:5432/v1" {
+		t.Errorf("unexpected nested url %q", cfg.Nested.URL)
+	}
+}
+
+func TestLoad_InterpolationFromDotenv(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := "ID_A=x\nID_B=${ID_MISSING:-fb}\nID_C=$${ID_A}\nID_D=\"${ID_A}-$$\"\nID_E='${ID_A:-y}$$'\nID_F=postgres://$ID_A@db\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, native := range []bool{false, true} {
+		opts := []Option{WithEnvDir(dir), WithOverlay()}
+		if native {
+			opts = append(opts, WithNativeDotenv())
+		}
+		var cfg struct {
+			B string `env:"ID_B"`
+			C string `env:"ID_C"`
+			D string `env:"ID_D"`
+			E string `env:"ID_E"`
+			F string `env:"ID_F"`
+		}
+		if err := Load(&cfg, opts...); err != nil {
+			t.Fatalf("expected no error (native: %v), got %v", native, err)
+		}
+		if cfg.B != "fb" || cfg.C != "${ID_A}" || cfg.D != "x-$" || cfg.E != "${ID_A:-y}$$" || cfg.F != "postgres://x@db" {
+			t.Errorf("expected fb, ${ID_A}, x-$, ${ID_A:-y}$$ and postgres://x@db (native: %v), got %+v", native, cfg)
+		}
+	}
+}
+
+func TestLoad_InterpolationErrors(t *testing.T) {
+	t.Setenv("I_TOKEN", "${I_SECRET:?must be provided by the vault agent}")
+
+	var cfg struct {
+		A     string `env:"I_A" default:"${I_B}"`
+		B     string `env:"I_B" default:"x${I_A}"`
+		Token string `env:"I_TOKEN"`
+		Bad   string `env:"I_BAD" default:"${I_UNCLOSED"`
+	}
+	err := parse(&cfg)
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", err)
+	}
+	if !strings.Contains(errs.Errs[0].Error(), "reference cycle I_A -> I_B -> I_A") {
+		t.Errorf("expected cycle error, got %v", errs.Errs[0])
+	}
+	if !strings.Contains(errs.Errs[2].Error(), "I_SECRET: must be provided by the vault agent") {
+		t.Errorf("expected :? message, got %v", errs.Errs[2])
+	}
+}
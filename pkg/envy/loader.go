@@ -3,17 +3,62 @@
 package envy
 
 import (
-	"fmt"
-	"os"
+	"bytes"
+	"io"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
-func loadEnvFile() error {
-	if _, err := os.Stat(".env"); err == nil {
-		if err := godotenv.Load(); err != nil {
-			return fmt.Errorf("error loading .env file: %w", err)
+// Placeholders stand in for $ and \$ while godotenv parses a file. They
+// are private use runes, files that do contain them are read with the
+// built-in parser.
+const (
+	dollarPlaceholder  = "\uE000"
+	escapedPlaceholder = "\uE001"
+)
+
+// parseEnvFile parses .env content with godotenv. godotenv expands $VAR
+// itself, which would break ${VAR:-fallback} and $$ before envy expands
+// them, so dollar signs are hidden from it and values keep them as written.
+// envy expands $VAR like godotenv did. A literal dollar is written $$, or
+// \$ as godotenv has it, which becomes $$. Single quoted values are
+// literal, their dollar signs are doubled. godotenv doesn't tell which
+// values were single quoted, the built-in parser does: files it rejects
+// are an error when they hold both quotes and dollar signs, rather than
+// guessing what the values mean.
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte(dollarPlaceholder)) || bytes.Contains(data, []byte(escapedPlaceholder)) {
+		return parseDotenv(bytes.NewReader(data))
+	}
+
+	hidden := strings.ReplaceAll(replaceEscapedDollars(string(data), escapedPlaceholder), "$", dollarPlaceholder)
+	values, err := godotenv.Parse(strings.NewReader(hidden))
+	if err != nil {
+		return nil, err
+	}
+	literal := map[string]bool{}
+	entries, err := parseDotenvEntries(bytes.NewReader(data))
+	if derr, ok := err.(*DotenvError); ok && bytes.ContainsRune(data, '\'') && bytes.ContainsRune(data, '$') {
+		derr.Msg += ", needed to tell single quoted values apart"
+		return nil, derr
+	}
+	for _, e := range entries {
+		literal[e.Key] = e.Literal
+	}
+
+	restore := strings.NewReplacer(escapedPlaceholder, "$$", dollarPlaceholder, "$")
+	restoreLiteral := strings.NewReplacer(escapedPlaceholder, `\$$`, dollarPlaceholder, "$$")
+	for k, v := range values {
+		if literal[k] {
+			values[k] = restoreLiteral.Replace(v)
+		} else {
+			values[k] = restore.Replace(v)
 		}
 	}
-	return nil
+	return values, nil
 }
@@ -2,6 +2,10 @@
 
 package envy
 
-func loadEnvFile() error {
-	return nil
+import "io"
+
+// parseEnvFile parses .env content with the built-in parser, slim builds
+// drop godotenv.
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	return parseDotenv(r)
 }
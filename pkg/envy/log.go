@@ -0,0 +1,114 @@
+package envy
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// WithLogger sets where envy reports warnings, slog.Default() by default.
+// Warnings carry the env key and field path as attributes, pass a nil
+// logger or one with slog.DiscardHandler to silence them.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		o.logger = logger
+	}
+}
+
+// WithWarnUnknown warns about variables starting with one of the prefixes
+// that no field reads, which usually are typos.
+func WithWarnUnknown(prefixes ...string) Option {
+	return func(o *options) {
+		o.warnUnknown = append(o.warnUnknown, prefixes...)
+	}
+}
+
+func (p *parser) warn(msg, key, path string, attrs ...slog.Attr) {
+	attrs = append([]slog.Attr{slog.String("key", key), slog.String("field", path)}, attrs...)
+	p.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "envy: "+msg, attrs...)
+}
+
+// lookupDeprecated reads the old names listed in the `deprecated` tag,
+// still honored after a rename, warning when one is used. The envPrefix of
+// the field applies to them as well.
+func (p *parser) lookupDeprecated(fp *fieldPlan) (alias, value string, src Source, ok bool) {
+	for _, alias = range fp.deprecated {
+		if value, src, ok = p.lookup(alias); ok {
+			p.warn("deprecated env var, rename it", alias, fp.path, slog.String("use", fp.key))
+			return alias, value, src, true
+		}
+	}
+	return "", "", nil, false
+}
+
+// warnUnknown logs the variables under the WithWarnUnknown prefixes that
+// no field looked up.
+func (p *parser) warnUnknown() {
+	for _, key := range p.unknownKeys(p.opts.warnUnknown) {
+		p.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "envy: unknown env var", slog.String("key", key))
+	}
+}
+
+// unknownKeys lists, sorted, the keys of the sources starting with one of
+// the prefixes that parse never looked up.
+func (p *parser) unknownKeys(prefixes []string) []string {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var unknown []string
+	for _, src := range p.opts.resolved {
+		lister, ok := src.(interface{ Keys() []string })
+		if !ok {
+			continue
+		}
+		for _, key := range lister.Keys() {
+			if seen[key] || p.used[key] || !hasAnyPrefix(key, prefixes) {
+				continue
+			}
+			seen[key] = true
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys lists the process environment, for unknown variable checks.
+func (envSource) Keys() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(env))
+	for _, kv := range env {
+		if k, _, ok := strings.Cut(kv, "="); ok && k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (m mapSource) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *dotenvSource) Keys() []string {
+	return mapSource(s.values).Keys()
+}
@@ -0,0 +1,101 @@
+package envy
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type renamedConfig struct {
+	Port  int             `env:"PORT" default:"8080" required:"true"`
+	Token string          `env:"TOKEN" deprecated:"API_TOKEN,AUTH_TOKEN"`
+	DB    renamedDBConfig `envPrefix:"DB_"`
+}
+
+type renamedDBConfig struct {
+	Host string `env:"HOST" deprecated:"HOSTNAME"`
+}
+
+func testLogger() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return slog.New(h), &buf
+}
+
+func TestLoad_Logger(t *testing.T) {
+	logger, buf := testLogger()
+	var cfg renamedConfig
+	err := Load(&cfg, WithLogger(logger), WithSources(MapSource(map[string]string{
+		"AUTH_TOKEN":  "abc",
+		"DB_HOSTNAME": "db",
+		"TOKEN_TTL":   "1h",
+		"DB_PORTT":    "5432",
+		"OTHER":       "x",
+	})), WithWarnUnknown("TOKEN_", "DB_"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Port != 8080 || cfg.Token != "abc" || cfg.DB.Host != "db" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	want := []string{
+		`level=WARN msg="envy: required env var not set, using default" key=PORT field=Port default=8080`,
+		`level=WARN msg="envy: deprecated env var, rename it" key=AUTH_TOKEN field=Token use=TOKEN`,
+		`level=WARN msg="envy: deprecated env var, rename it" key=DB_HOSTNAME field=DB.Host use=DB_HOST`,
+		`level=WARN msg="envy: unknown env var" key=DB_PORTT`,
+		`level=WARN msg="envy: unknown env var" key=TOKEN_TTL`,
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected log:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLoad_DeprecatedPrecedence(t *testing.T) {
+	logger, buf := testLogger()
+	var cfg renamedConfig
+	err := Load(&cfg, WithLogger(logger), WithSources(MapSource(map[string]string{
+		"PORT":      "9090",
+		"TOKEN":     "new",
+		"API_TOKEN": "old",
+	})))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Token != "new" {
+		t.Errorf("expected the new key to win, got %q", cfg.Token)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warnings:\n%s", buf)
+	}
+}
+
+func TestLoad_SecretDefaultMasked(t *testing.T) {
+	logger, buf := testLogger()
+	var cfg struct {
+		Key string `env:"KEY" default:"s3cret" required:"true" secret:"true"`
+	}
+	if err := Load(&cfg, WithLogger(logger), WithSources(MapSource(nil))); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("expected the secret default to be masked, got:\n%s", buf)
+	}
+}
+
+func TestLoad_NilLogger(t *testing.T) {
+	var cfg struct {
+		Key string `env:"KEY" default:"k" required:"true"`
+	}
+	if err := Load(&cfg, WithLogger(nil), WithSources(MapSource(nil))); err != nil || cfg.Key != "k" {
+		t.Errorf("expected the default without a warning, got %q (error: %v)", cfg.Key, err)
+	}
+}
@@ -0,0 +1,302 @@
+package envy
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal returns the env values of v, a struct or a pointer to one, the
+// reverse of Load: the same tags, envPrefix nesting and separators apply,
+// so loading the result gives back an equal struct.
+//
+// Nil pointers are left out, so they load as nil again. Values are
+// written as is, secrets included, and dollar signs are doubled so they
+// survive interpolation. Values starting with ref+ or of the form
+// ENC[...] would be resolved or decrypted when loaded and fail with
+// ErrUnsupported. A few values don't survive a round trip: empty values
+// fall back to their default, empty slices and maps load as nil and times
+// keep the precision of their layout.
+func Marshal(v any) (map[string]string, error) {
+	entries, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(entries))
+	for _, e := range entries {
+		values[e.key] = e.value
+	}
+	return values, nil
+}
+
+// WriteDotenv writes the values of Marshal as a .env file, in field
+// order, quoted so that both godotenv and the built-in parser read them
+// back unchanged. Values no quoting keeps intact, such as one with a single
+// quote and ending in a backslash, fail with ErrUnsupported.
+func WriteDotenv(w io.Writer, v any) error {
+	entries, err := marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	var errs []*FieldError
+	for _, e := range entries {
+		value, err := dotenvValue(e.value)
+		if err != nil {
+			errs = append(errs, &FieldError{Key: e.key, Field: e.path, Err: fmt.Errorf("%w: %v", ErrUnsupported, err)})
+			continue
+		}
+		fmt.Fprintf(&b, "%s=%s\n", e.key, value)
+	}
+	if len(errs) > 0 {
+		return &Errors{Errs: errs}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// marshal formats the fields of v in declaration order.
+func marshal(v any) ([]marshaledField, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("target must be a struct or a pointer to a struct")
+	}
+
+	m := &marshaler{}
+	m.marshalStruct(val, planFor(val.Type()).fields)
+	if len(m.errs) > 0 {
+		return nil, &Errors{Errs: m.errs}
+	}
+	return m.entries, nil
+}
+
+// marshaler holds the state of a single Marshal run.
+type marshaler struct {
+	entries []marshaledField
+	errs    []*FieldError
+}
+
+// marshaledField is the env value of a field.
+type marshaledField struct {
+	key, path, value string
+}
+
+// marshalStruct formats the fields of val following their plans, like
+// parseStruct reads them.
+func (m *marshaler) marshalStruct(val reflect.Value, fields []fieldPlan) {
+	for i := range fields {
+		fp := &fields[i]
+		field := val.Field(fp.index)
+
+		if fp.nested != nil {
+			if fp.ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			m.marshalStruct(field, fp.nested)
+			continue
+		}
+
+		// A nil pointer has no value, a present one would allocate it
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			continue
+		}
+		value, err := formatField(field, fp)
+		if err != nil {
+			if !errors.Is(err, ErrUnsupported) {
+				err = fmt.Errorf("%w: %w", ErrUnsupported, err)
+			}
+			m.errs = append(m.errs, &FieldError{Key: fp.key, Field: fp.path, Err: err})
+			continue
+		}
+		// There is no escape for these, Load would resolve or decrypt them
+		if strings.HasPrefix(value, refPrefix) || isEncrypted(value) {
+			err := fmt.Errorf("%w: value would be loaded as a secret reference or an encrypted value", ErrUnsupported)
+			m.errs = append(m.errs, &FieldError{Key: fp.key, Field: fp.path, Err: err})
+			continue
+		}
+		m.entries = append(m.entries, marshaledField{fp.key, fp.path, strings.ReplaceAll(value, "$", "$$")})
+	}
+}
+
+// formatField is the reverse of setField, checking types in the same
+// order.
+func formatField(field reflect.Value, fp *fieldPlan) (string, error) {
+	// Registered decoders have no encoder, the type has to format itself
+	if _, ok := lookupDecoder(field.Type()); ok {
+		if s, ok, err := formatText(field); ok {
+			return s, err
+		}
+		if s, ok := field.Interface().(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+		return "", fmt.Errorf("%w: %v has a decoder but no MarshalText or String method", ErrUnsupported, field.Type())
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return "", errors.New("nil pointer")
+		}
+		return formatField(field.Elem(), fp)
+	}
+
+	switch field.Type() {
+	case durationType:
+		return time.Duration(field.Int()).String(), nil
+	case timeType:
+		layout := fp.layout
+		if layout == "" {
+			// Parsing RFC3339 accepts fractional seconds
+			layout = time.RFC3339Nano
+		}
+		return field.Interface().(time.Time).Format(layout), nil
+	}
+
+	if reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		if s, ok, err := formatText(field); ok {
+			return s, err
+		}
+		return "", fmt.Errorf("%w: %v has no MarshalText method", ErrUnsupported, field.Type())
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), nil
+	case reflect.Slice:
+		return formatSlice(field, fp)
+	case reflect.Map:
+		return formatMap(field, fp)
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, field.Type())
+	}
+}
+
+// formatText calls MarshalText when the field implements
+// encoding.TextMarshaler. It reports false when it does not.
+func formatText(field reflect.Value) (string, bool, error) {
+	if !field.CanAddr() {
+		addressable := reflect.New(field.Type()).Elem()
+		addressable.Set(field)
+		field = addressable
+	}
+	tm, ok := field.Addr().Interface().(encoding.TextMarshaler)
+	if !ok {
+		return "", false, nil
+	}
+	text, err := tm.MarshalText()
+	if err != nil {
+		return "", true, fmt.Errorf("invalid %v: %w", field.Type(), err)
+	}
+	return string(text), true, nil
+}
+
+func formatSlice(field reflect.Value, fp *fieldPlan) (string, error) {
+	elemType := field.Type().Elem()
+	if isContainer(elemType.Kind()) && !isValueType(elemType) {
+		return "", fmt.Errorf("%w: slice element %v", ErrUnsupported, elemType)
+	}
+
+	elems := make([]string, field.Len())
+	for i := range elems {
+		s, err := formatField(field.Index(i), fp)
+		if err != nil {
+			return "", err
+		}
+		if err := checkEntry(s, fp.sep, ""); err != nil {
+			return "", fmt.Errorf("slice element %d: %w", i, err)
+		}
+		elems[i] = s
+	}
+	return strings.Join(elems, fp.sep), nil
+}
+
+func formatMap(field reflect.Value, fp *fieldPlan) (string, error) {
+	elemType := field.Type().Elem()
+	if isContainer(elemType.Kind()) && !isValueType(elemType) {
+		return "", fmt.Errorf("%w: map value %v", ErrUnsupported, elemType)
+	}
+
+	entries := make([]string, 0, field.Len())
+	iter := field.MapRange()
+	for iter.Next() {
+		k, err := formatField(iter.Key(), fp)
+		if err != nil {
+			return "", err
+		}
+		if err := checkEntry(k, fp.sep, fp.kvSep); err != nil {
+			return "", fmt.Errorf("map key: %w", err)
+		}
+		v, err := formatField(iter.Value(), fp)
+		if err != nil {
+			return "", err
+		}
+		if v != "" {
+			if err := checkEntry(v, fp.sep, ""); err != nil {
+				return "", fmt.Errorf("map value for key %q: %w", k, err)
+			}
+		}
+		entries = append(entries, k+fp.kvSep+v)
+	}
+	// Map order is random, sorted entries keep the output stable
+	slices.Sort(entries)
+	return strings.Join(entries, fp.sep), nil
+}
+
+// checkEntry reports list entries that would be split or trimmed when
+// parsed again.
+func checkEntry(s, sep, kvSep string) error {
+	switch {
+	case s == "":
+		return errors.New("empty entries are skipped when parsing")
+	case s != strings.TrimSpace(s):
+		return fmt.Errorf("%q has surrounding spaces", s)
+	case strings.Contains(s, sep):
+		return fmt.Errorf("%q contains the separator %q", s, sep)
+	case kvSep != "" && strings.Contains(s, kvSep):
+		return fmt.Errorf("%q contains the separator %q", s, kvSep)
+	}
+	return nil
+}
+
+// dotenvValue quotes s, with dollar signs already doubled, for a .env
+// file. godotenv mishandles a few escapes in double quotes, so single
+// quotes are preferred, except for dollar signs: single quoted values are
+// taken literally and $$ would stay doubled. Values neither quoting keeps
+// intact are refused.
+func dotenvValue(s string) (string, error) {
+	if !strings.ContainsFunc(s, func(r rune) bool { return !isPlainRune(r) }) {
+		return s, nil
+	}
+	if !strings.ContainsAny(s, "$'\r") && !strings.HasSuffix(s, `\`) {
+		return "'" + s + "'", nil
+	}
+	if strings.HasSuffix(s, `\`) || strings.HasSuffix(s, `"`) {
+		return "", errors.New("value can't be quoted for a .env file")
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(s) + `"`, nil
+}
+
+// isPlainRune reports whether r can appear in an unquoted value.
+func isPlainRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:,@+=%", r)
+}
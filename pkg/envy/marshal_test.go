@@ -0,0 +1,385 @@
+package envy
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+type marshalConfig struct {
+	Port     int               `env:"PORT" default:"8080"`
+	Hosts    []string          `env:"HOSTS" sep:";"`
+	Limits   map[string]int    `env:"LIMITS" kvsep:"="`
+	Timeout  time.Duration     `env:"TIMEOUT"`
+	Since    time.Time         `env:"SINCE" layout:"2006-01-02"`
+	Password string            `env:"PASSWORD" secret:"true"`
+	Greeting string            `env:"GREETING"`
+	Bind     netip.Addr        `env:"BIND"`
+	Labels   map[string]string `env:"LABELS"`
+	Database struct {
+		Host string `env:"HOST"`
+		Port *int   `env:"PORT"`
+	} `envPrefix:"DB_"`
+	Replica *struct {
+		DSN string `env:"DSN"`
+	} `envPrefix:"REPLICA_"`
+	Ignored string
+}
+
+func TestMarshal(t *testing.T) {
+	var cfg marshalConfig
+	cfg.Port = 9090
+	cfg.Hosts = []string{"a.example.com", "b.example.com"}
+	cfg.Limits = map[string]int{"write": 10, "read": 100}
+	cfg.Timeout = 90 * time.Second
+	cfg.Since = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg.Password = "pa$$word"
+	cfg.Greeting = "hello world"
+	cfg.Bind = netip.MustParseAddr("10.0.0.1")
+	cfg.Database.Host = "db.internal"
+	cfg.Ignored = "not marshaled"
+
+	values, err := Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := map[string]string{
+		"PORT":     "9090",
+		"HOSTS":    "a.example.com;b.example.com",
+		"LIMITS":   "read=100,write=10",
+		"TIMEOUT":  "1m30s",
+		"SINCE":    "2024-03-01",
+		"PASSWORD": "pa$$$$word",
+		"GREETING": "hello world",
+		"BIND":     "10.0.0.1",
+		"LABELS":   "",
+		"DB_HOST":  "db.internal",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	// Loading the values gives the struct back
+	loaded, err := Parse[marshalConfig](WithSources(MapSource(values)))
+	if err != nil {
+		t.Fatalf("expected no error loading, got %v", err)
+	}
+	cfg.Ignored = ""
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("expected %+v, got %+v", cfg, loaded)
+	}
+}
+
+func TestMarshal_Pointers(t *testing.T) {
+	var cfg marshalConfig
+	port := 0
+	cfg.Database.Port = &port
+	cfg.Replica = &struct {
+		DSN string `env:"DSN"`
+	}{}
+
+	values, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if v, ok := values["DB_PORT"]; !ok || v != "0" {
+		t.Errorf("expected DB_PORT=0, got %q (present: %v)", v, ok)
+	}
+	if _, ok := values["REPLICA_DSN"]; !ok {
+		t.Error("expected REPLICA_DSN to be set for a non-nil Replica")
+	}
+
+	loaded, err := Parse[marshalConfig](WithSources(MapSource(values)))
+	if err != nil {
+		t.Fatalf("expected no error loading, got %v", err)
+	}
+	if loaded.Database.Port == nil || *loaded.Database.Port != 0 || loaded.Replica == nil {
+		t.Errorf("expected pointers to be set again, got %+v", loaded)
+	}
+}
+
+func TestMarshal_Errors(t *testing.T) {
+	type config struct {
+		Level logLevel       `env:"LEVEL"`
+		Hosts []string       `env:"HOSTS"`
+		Tags  map[string]int `env:"TAGS"`
+		Ch    chan int       `env:"CH"`
+		Ref   string         `env:"REF"`
+		Enc   []string       `env:"ENC"`
+	}
+	cfg := config{
+		Hosts: []string{"a,b"},
+		Tags:  map[string]int{" padded": 1},
+		Ch:    make(chan int),
+		Ref:   "ref+file:///etc/passwd",
+		Enc:   []string{"ENC[AES256_GCM", "x]"},
+	}
+
+	_, err := Marshal(&cfg)
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 6 {
+		t.Fatalf("expected 6 errors, got %v", err)
+	}
+	for _, fe := range errs.Errs {
+		if !errors.Is(fe, ErrUnsupported) {
+			t.Errorf("expected ErrUnsupported for %s, got %v", fe.Key, fe)
+		}
+	}
+	if !strings.Contains(err.Error(), `"a,b" contains the separator ","`) {
+		t.Errorf("expected the separator to be named, got %v", err)
+	}
+	if strings.Contains(err.Error(), "/etc/passwd") || !strings.Contains(errs.Errs[4].Error(), "secret reference") {
+		t.Errorf("expected REF to be refused without quoting it, got %v", errs.Errs[4])
+	}
+
+	if _, err := Marshal("not a struct"); err == nil {
+		t.Error("expected an error for a non-struct target")
+	}
+}
+
+func TestWriteDotenv(t *testing.T) {
+	type config struct {
+		Plain  string `env:"PLAIN"`
+		Spaced string `env:"SPACED"`
+		Dollar string `env:"DOLLAR"`
+		Quoted string `env:"QUOTED"`
+		Lines  string `env:"LINES"`
+		Empty  string `env:"EMPTY"`
+		Nested struct {
+			Name string `env:"NAME"`
+		} `envPrefix:"NESTED_"`
+	}
+	cfg := config{
+		Plain:  "example.com:8080",
+		Spaced: "  hello # world",
+		Dollar: "${HOME}",
+		Quoted: `it's "$5"!`,
+		Lines:  "one\ntwo",
+	}
+	cfg.Nested.Name = "nested"
+
+	var buf bytes.Buffer
+	if err := WriteDotenv(&buf, &cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `PLAIN=example.com:8080
+SPACED='  hello # world'
+DOLLAR="$${HOME}"
+QUOTED="it's \"$$5\"!"
+LINES='one
+two'
+EMPTY=
+NESTED_NAME=nested
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	for _, native := range []bool{false, true} {
+		src, err := newDotenvSource(".env", buf.Bytes(), native)
+		if err != nil {
+			t.Fatalf("expected no error parsing (native: %v), got %v", native, err)
+		}
+		loaded, err := Parse[config](WithSources(src))
+		if err != nil {
+			t.Fatalf("expected no error loading (native: %v), got %v", native, err)
+		}
+		if loaded != cfg {
+			t.Errorf("native: %v: expected %+v, got %+v", native, cfg, loaded)
+		}
+	}
+
+	// Neither quoting survives both parsers
+	cfg.Quoted = `it's \`
+	err := WriteDotenv(&buf, &cfg)
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "QUOTED") {
+		t.Errorf("expected ErrUnsupported for QUOTED, got %v", err)
+	}
+}
+
+type roundTripConfig struct {
+	Name    string            `env:"NAME"`
+	Port    int               `env:"PORT"`
+	Count   uint16            `env:"COUNT"`
+	Ratio   float64           `env:"RATIO"`
+	Enabled bool              `env:"ENABLED"`
+	Timeout time.Duration     `env:"TIMEOUT"`
+	Started time.Time         `env:"STARTED"`
+	Bind    netip.Addr        `env:"BIND"`
+	Tags    []string          `env:"TAGS" sep:";"`
+	Limits  map[string]int    `env:"LIMITS" kvsep:"="`
+	Labels  map[string]string `env:"LABELS"`
+	DB      struct {
+		Host string `env:"HOST"`
+		Port *int   `env:"PORT"`
+	} `envPrefix:"DB_"`
+	Replica *struct {
+		DSN string `env:"DSN" secret:"true"`
+	} `envPrefix:"REPLICA_"`
+}
+
+// Generate draws configs whose values all survive a round trip: list
+// entries are trimmed, non-empty and free of separators, and empty lists
+// are nil. Strings may look like secret references or encrypted values,
+// which Marshal refuses.
+func (roundTripConfig) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(generateRoundTrip(r, size, false))
+}
+
+// dotenvRoundTripConfig draws roundTripConfig values that WriteDotenv can
+// quote, refused values are covered by TestWriteDotenv.
+type dotenvRoundTripConfig struct {
+	Config roundTripConfig
+}
+
+func (dotenvRoundTripConfig) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(dotenvRoundTripConfig{generateRoundTrip(r, size, true)})
+}
+
+func generateRoundTrip(r *rand.Rand, size int, quotable bool) roundTripConfig {
+	const alphabet = "abcXYZ019 _-.=:,;#$'\"\\\n\t{}é"
+	trimmed := func(s string) string {
+		s = strings.TrimSpace(s)
+		// Neither quoting keeps a value ending in a backslash, or with a
+		// single quote and ending in a double one
+		if quotable && (strings.HasSuffix(s, `\`) || strings.HasSuffix(s, `"`)) {
+			s += "x"
+		}
+		return s
+	}
+	str := func(chars string) string {
+		b := make([]rune, r.Intn(size+1))
+		runes := []rune(chars)
+		for i := range b {
+			b[i] = runes[r.Intn(len(runes))]
+		}
+		if quotable {
+			return trimmed(string(b))
+		}
+		return string(b)
+	}
+	// value draws a whole field value, sometimes one Load would resolve or
+	// decrypt
+	value := func() string {
+		switch s := str(alphabet); r.Intn(8) {
+		case 0:
+			return refPrefix + s
+		case 1:
+			return encPrefix + s + encSuffix
+		default:
+			return s
+		}
+	}
+	entry := func() string {
+		return "k" + trimmed(str("abcXYZ019 _-.$'\"\\é"))
+	}
+
+	var cfg roundTripConfig
+	cfg.Name = value()
+	cfg.Port = r.Int() - r.Int()
+	cfg.Count = uint16(r.Intn(1 << 16))
+	cfg.Ratio = r.NormFloat64() * 1e6
+	cfg.Enabled = r.Intn(2) == 1
+	cfg.Timeout = time.Duration(r.Int63())
+	cfg.Started = time.Unix(r.Int63n(253402300799), r.Int63n(1e9)).UTC()
+	cfg.Bind = netip.AddrFrom4([4]byte{byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256))})
+	for range r.Intn(4) {
+		cfg.Tags = append(cfg.Tags, entry())
+	}
+	for range r.Intn(4) {
+		if cfg.Limits == nil {
+			cfg.Limits = map[string]int{}
+		}
+		cfg.Limits[entry()] = r.Intn(1000)
+	}
+	for range r.Intn(4) {
+		if cfg.Labels == nil {
+			cfg.Labels = map[string]string{}
+		}
+		cfg.Labels[entry()] = trimmed(str("abc019 .=$'\"\\"))
+	}
+	cfg.DB.Host = value()
+	if r.Intn(2) == 1 {
+		port := r.Intn(65536)
+		cfg.DB.Port = &port
+	}
+	if r.Intn(2) == 1 {
+		cfg.Replica = &struct {
+			DSN string `env:"DSN" secret:"true"`
+		}{DSN: value()}
+	}
+	return cfg
+}
+
+// refusedOnly reports whether err only refuses values Load would resolve
+// or decrypt.
+func refusedOnly(err error) bool {
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, fe := range errs.Errs {
+		if !errors.Is(fe, ErrUnsupported) || !strings.Contains(fe.Error(), "secret reference or an encrypted value") {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	roundTrip := func(cfg roundTripConfig) bool {
+		values, err := Marshal(&cfg)
+		if refusedOnly(err) {
+			return true
+		}
+		if err != nil {
+			t.Logf("marshaling %+v: %v", cfg, err)
+			return false
+		}
+		loaded, err := Parse[roundTripConfig](WithSources(MapSource(values)))
+		if err != nil {
+			t.Logf("loading %v: %v", values, err)
+			return false
+		}
+		return reflect.DeepEqual(loaded, cfg)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteDotenv_RoundTrip(t *testing.T) {
+	roundTrip := func(c dotenvRoundTripConfig) bool {
+		var buf bytes.Buffer
+		err := WriteDotenv(&buf, &c.Config)
+		if refusedOnly(err) {
+			return true
+		}
+		if err != nil {
+			t.Logf("writing %+v: %v", c.Config, err)
+			return false
+		}
+		for _, native := range []bool{false, true} {
+			src, err := newDotenvSource(".env", buf.Bytes(), native)
+			if err != nil {
+				t.Logf("parsing (native: %v):\n%s\n%v", native, buf.String(), err)
+				return false
+			}
+			loaded, err := Parse[roundTripConfig](WithSources(src))
+			if err != nil || !reflect.DeepEqual(loaded, c.Config) {
+				t.Logf("loading (native: %v):\n%s\n%+v, %v", native, buf.String(), loaded, err)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
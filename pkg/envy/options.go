@@ -0,0 +1,97 @@
+package envy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Option configures a Loader, or a single Load call.
+type Option func(*options)
+
+type options struct {
+	sources     []Source
+	envFiles    []string
+	envDir      string
+	envVar      string
+	fileSecrets bool
+	native      bool
+	overlay     bool
+	report      *Report
+	logger      *slog.Logger
+	warnUnknown []string
+	strict      []string
+
+	ctx            context.Context
+	resolveTimeout time.Duration
+	keyFile        string
+	keyEnv         string
+
+	resolved []Source // sources, or the process environment by default
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		envFiles:       defaultEnvFiles,
+		envDir:         ".",
+		envVar:         "APP_ENV",
+		logger:         slog.Default(),
+		ctx:            context.Background(),
+		resolveTimeout: defaultResolveTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.resolved = o.sources
+	if len(o.resolved) == 0 {
+		o.resolved = []Source{EnvSource()}
+	}
+	return o
+}
+
+// WithSources sets the ordered list of sources values are read from, the
+// first source holding a key wins. By default a Loader reads the process
+// environment after loading the .env files into it, with explicit sources
+// nothing else is consulted and the process environment is left untouched.
+func WithSources(sources ...Source) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, sources...)
+	}
+}
+
+// WithFileSecrets lets every field be read from the file named by its
+// <KEY>_FILE variable, as if each field were tagged `file:"allow"`.
+func WithFileSecrets() Option {
+	return func(o *options) {
+		o.fileSecrets = true
+	}
+}
+
+// WithOverlay keeps .env file values in a private overlay consulted only
+// by Load, behind the process environment, instead of exporting them with
+// os.Setenv. Child processes and other packages won't see them, use Apply
+// to export them explicitly.
+func WithOverlay() Option {
+	return func(o *options) {
+		o.overlay = true
+	}
+}
+
+// WithNativeDotenv reads .env files with envy's built-in parser instead of
+// godotenv. Builds with the libgo_envy_slim tag always use it.
+func WithNativeDotenv() Option {
+	return func(o *options) {
+		o.native = true
+	}
+}
+
+// readEnvFile reads a .env file with the parser the options ask for.
+func (o *options) readEnvFile(path string) (*dotenvSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return newDotenvSource(path, data, o.native)
+}
@@ -0,0 +1,148 @@
+package envy
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/moeghifar/libgo/pkg/envy/envyrt"
+)
+
+// plans caches the compiled plan of every config type parsed so far, so
+// repeated loads skip walking the type, reading its tags and compiling
+// patterns.
+var plans sync.Map // reflect.Type -> *plan
+
+// plan is the compiled form of a config struct type.
+type plan struct {
+	fields   []fieldPlan
+	defaults map[string]string // default tags by env key, for interpolation
+	files    map[string]bool   // keys tagged `file:"allow"`, for interpolation
+}
+
+// fieldPlan is a struct field with its key, path and tags resolved once.
+// Nested structs and struct pointers hold the plans of their own fields.
+type fieldPlan struct {
+	index       int
+	structField reflect.StructField
+	key         string // env key, envPrefix included
+	path        string // field path used in errors and reports
+
+	nested []fieldPlan // fields of a nested struct, non-nil even when empty
+	ptr    bool        // the nested struct is behind a pointer
+
+	def        string
+	required   bool
+	secret     bool
+	file       bool // `file:"allow"`
+	nonempty   bool
+	validates  bool // has at least one validation tag
+	deprecated []string
+
+	layout     string // `layout` of time values, empty for the default
+	sep, kvSep string // list separators, defaults applied
+
+	// Validation tags, compiled when validates is set
+	bounds  []rangeBound
+	oneOf   []string
+	pattern *regexp.Regexp
+	rules   []func(string) error
+	tagErr  error // An invalid validation tag
+}
+
+// planFor returns the plan of typ, compiling it on first use.
+func planFor(typ reflect.Type) *plan {
+	if cached, ok := plans.Load(typ); ok {
+		return cached.(*plan)
+	}
+	pl := &plan{defaults: map[string]string{}, files: map[string]bool{}}
+	pl.fields = compileFields(typ, "", "", pl)
+	cached, _ := plans.LoadOrStore(typ, pl)
+	return cached.(*plan)
+}
+
+// compileFields builds the plans of the fields of typ, skipping fields
+// without an env tag the same way parsing does.
+func compileFields(typ reflect.Type, prefix, path string, pl *plan) []fieldPlan {
+	var fields []fieldPlan
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		fp := fieldPlan{index: i, structField: structField, path: structField.Name}
+		if path != "" {
+			fp.path = path + "." + structField.Name
+		}
+
+		// A struct with an env tag is a value, unsupported unless it
+		// decodes itself
+		key := structField.Tag.Get("env")
+		t := structField.Type
+		if key == "" && t.Kind() == reflect.Ptr && isNestedStruct(t.Elem()) {
+			t, fp.ptr = t.Elem(), true
+		}
+		if key == "" && isNestedStruct(t) {
+			envPrefix, tagged := structField.Tag.Lookup("envPrefix")
+			fp.nested = compileFields(t, prefix+envPrefix, fp.path, pl)
+			// A struct pointer is a config block only when tagged or holding
+			// env vars, not say a *slog.Logger kept next to the config
+			if fp.ptr && !tagged && !hasKeys(fp.nested) {
+				continue
+			}
+			if fp.nested == nil {
+				fp.nested = []fieldPlan{}
+			}
+			fields = append(fields, fp)
+			continue
+		}
+
+		if key == "" {
+			continue
+		}
+		fp.key = prefix + key
+		fp.def = structField.Tag.Get("default")
+		fp.required = structField.Tag.Get("required") == "true"
+		fp.secret = structField.Tag.Get("secret") == "true"
+		fp.file = structField.Tag.Get("file") == "allow"
+		fp.nonempty = hasRule(structField, "nonempty")
+		for _, tag := range []string{"min", "max", "oneof", "pattern", "validate"} {
+			fp.validates = fp.validates || structField.Tag.Get(tag) != ""
+		}
+		if fp.validates {
+			fp.compileValidation(structField)
+		}
+		fp.layout = structField.Tag.Get("layout")
+		fp.sep, fp.kvSep = envyrt.Separators(structField.Tag.Get("sep"), structField.Tag.Get("kvsep"))
+		if deprecated := structField.Tag.Get("deprecated"); deprecated != "" {
+			for _, name := range strings.Split(deprecated, ",") {
+				fp.deprecated = append(fp.deprecated, prefix+strings.TrimSpace(name))
+			}
+		}
+		if fp.def != "" {
+			pl.defaults[fp.key] = fp.def
+		}
+		if fp.file {
+			pl.files[fp.key] = true
+		}
+		fields = append(fields, fp)
+	}
+	return fields
+}
+
+// hasKeys reports whether fields or their nested structs have an env tag.
+func hasKeys(fields []fieldPlan) bool {
+	found := false
+	walkPlan(fields, func(*fieldPlan) { found = true })
+	return found
+}
+
+// walkPlan calls fn for every field with an env tag, descending into
+// nested structs and struct pointers.
+func walkPlan(fields []fieldPlan, fn func(fp *fieldPlan)) {
+	for i := range fields {
+		if fields[i].nested != nil {
+			walkPlan(fields[i].nested, fn)
+			continue
+		}
+		fn(&fields[i])
+	}
+}
@@ -0,0 +1,226 @@
+package envy
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type planConfig struct {
+	Port    int           `env:"PORT" default:"8080" min:"1" max:"65535"`
+	Host    string        `env:"HOST" required:"true"`
+	Debug   bool          `env:"DEBUG"`
+	Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+	Hosts   []string      `env:"HOSTS" default:"a,b"`
+	URL     string        `env:"URL" default:"http://${HOST}:${PORT}"`
+	DB      struct {
+		Host string `env:"HOST" default:"localhost"`
+		Port int    `env:"PORT" default:"5432"`
+	} `envPrefix:"DB_"`
+	Cache *struct {
+		Size int `env:"SIZE"`
+	} `envPrefix:"CACHE_"`
+}
+
+var planSource = WithSources(MapSource(map[string]string{
+	"HOST":    "example.com",
+	"DEBUG":   "true",
+	"DB_PORT": "6543",
+}))
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse[planConfig](planSource)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Host != "example.com" || !cfg.Debug || cfg.Port != 8080 || cfg.DB.Port != 6543 || cfg.DB.Host != "localhost" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.URL != "http://example.com:8080" {
+		t.Errorf("expected interpolated URL, got %q", cfg.URL)
+	}
+	if cfg.Cache != nil {
+		t.Errorf("expected nil Cache, got %+v", cfg.Cache)
+	}
+
+	// The second parse runs from the cached plan
+	again, err := Parse[planConfig](planSource)
+	if err != nil || !reflect.DeepEqual(again, cfg) {
+		t.Errorf("expected %+v from the cached plan, got %+v, %v", cfg, again, err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse[planConfig](WithSources(MapSource(nil)))
+	if !errors.Is(err, ErrRequired) {
+		t.Errorf("expected ErrRequired, got %v", err)
+	}
+
+	if _, err := Parse[*planConfig](planSource); err == nil {
+		t.Error("expected an error for a pointer type")
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	if cfg := MustParse[planConfig](planSource); cfg.Host != "example.com" {
+		t.Errorf("expected example.com, got %q", cfg.Host)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "HOST") {
+			t.Errorf("expected a panic about HOST, got %v", r)
+		}
+	}()
+	MustParse[planConfig](WithSources(MapSource(nil)))
+}
+
+type planValue struct{ A, B string }
+
+type planDecoded struct {
+	V planValue `env:"V"`
+}
+
+func TestPlan_RegisterDecoderInvalidates(t *testing.T) {
+	// Without a decoder the struct can't be parsed from V
+	var cfg planDecoded
+	if err := Load(&cfg, WithSources(MapSource(map[string]string{"V": "x/y"}))); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	RegisterDecoder(reflect.TypeOf(planValue{}), func(s string) (any, error) {
+		a, b, _ := strings.Cut(s, "/")
+		return planValue{a, b}, nil
+	})
+	defer RegisterDecoder(reflect.TypeOf(planValue{}), nil)
+
+	if err := Load(&cfg, WithSources(MapSource(map[string]string{"V": "x/y"}))); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.V != (planValue{"x", "y"}) {
+		t.Errorf("expected {x y}, got %+v", cfg.V)
+	}
+}
+
+func TestPlan_EmptyNestedStruct(t *testing.T) {
+	var cfg struct {
+		Empty   struct{}
+		Pointer *struct{}    `envPrefix:"POINTER_"`
+		Logger  *slog.Logger // Not a config block, no envPrefix nor env vars
+		Client  *http.Client
+		Port    int `env:"PORT"`
+	}
+	if err := Load(&cfg, WithSources(MapSource(map[string]string{"PORT": "80"}))); err != nil || cfg.Pointer != nil {
+		t.Errorf("expected no error and a nil pointer, got %v, %+v", err, cfg)
+	}
+	values, err := Marshal(&cfg)
+	if err != nil || !reflect.DeepEqual(values, map[string]string{"PORT": "80"}) {
+		t.Errorf("expected only PORT, got %v, %v", values, err)
+	}
+	var b strings.Builder
+	if err := Dump(&cfg, &b); err != nil || !strings.Contains(b.String(), "Pointer  -     <nil>") {
+		t.Errorf("expected a nil Pointer line, got %v:\n%s", err, b.String())
+	}
+	if strings.Contains(b.String(), "Logger") || strings.Contains(b.String(), "Client") {
+		t.Errorf("expected untagged struct pointers to be left out, got:\n%s", b.String())
+	}
+	if redacted := Redacted(&cfg); len(redacted) != 2 {
+		t.Errorf("expected only Pointer and Port, got %v", redacted)
+	}
+}
+
+func TestPlan_TaggedStructPointer(t *testing.T) {
+	var cfg struct {
+		U *url.URL `env:"X_URL"`
+	}
+	err := Load(&cfg, WithSources(MapSource(map[string]string{"X_URL": "https://example.com"})))
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 1 || errs.Errs[0].Key != "X_URL" || !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for X_URL, got %v", err)
+	}
+	if fields, err := Describe(&cfg); err != nil || len(fields) != 1 || fields[0].Key != "X_URL" {
+		t.Errorf("expected X_URL to be described, got %+v, %v", fields, err)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Parse[planConfig](planSource); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParse_Uncached compiles the plan on every load, the cost the
+// cache saves.
+func BenchmarkParse_Uncached(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		plans.Clear()
+		if _, err := Parse[planConfig](planSource); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPlan_CompiledTags(t *testing.T) {
+	type config struct {
+		Name  string            `env:"NAME" pattern:"^[a-z]+$" oneof:"api worker"`
+		Port  int               `env:"PORT" min:"1" max:"65535"`
+		Since time.Time         `env:"SINCE" layout:"2006-01-02"`
+		Tags  map[string]string `env:"TAGS" sep:";" kvsep:"="`
+		Bad   string            `env:"BAD" pattern:"["`
+		Range bool              `env:"RANGE" min:"1"`
+	}
+	fields := planFor(reflect.TypeOf(config{})).fields
+
+	if fp := fields[0]; fp.pattern == nil || !reflect.DeepEqual(fp.oneOf, []string{"api", "worker"}) {
+		t.Errorf("expected a compiled pattern and oneof set, got %v, %v", fp.pattern, fp.oneOf)
+	}
+	if fp := fields[1]; len(fp.bounds) != 2 || fp.tagErr != nil {
+		t.Errorf("expected 2 bounds, got %d (error: %v)", len(fp.bounds), fp.tagErr)
+	}
+	if fp := fields[2]; fp.layout != "2006-01-02" || fp.sep != "," {
+		t.Errorf("expected the layout and the default separator, got %q, %q", fp.layout, fp.sep)
+	}
+	if fp := fields[3]; fp.sep != ";" || fp.kvSep != "=" {
+		t.Errorf("expected ; and =, got %q, %q", fp.sep, fp.kvSep)
+	}
+	if fp := fields[4]; fp.tagErr == nil || !strings.Contains(fp.tagErr.Error(), "invalid pattern tag") {
+		t.Errorf("expected the invalid pattern to be kept, got %v", fp.tagErr)
+	}
+
+	// Invalid tags fail the fields they are on, on every load
+	for range 2 {
+		err := Load(&config{}, WithSources(MapSource(map[string]string{"BAD": "x", "RANGE": "true"})))
+		var errs *Errors
+		if !errors.As(err, &errs) || len(errs.Errs) != 2 || !errors.Is(err, ErrValidation) {
+			t.Fatalf("expected 2 validation errors, got %v", err)
+		}
+		if !strings.Contains(errs.Errs[1].Error(), "min tag is not supported for bool") {
+			t.Errorf("expected the unsupported min tag, got %v", errs.Errs[1])
+		}
+	}
+
+	// They fail loads that don't set them too, inside unused blocks as well
+	var unset struct {
+		config
+		Block *struct {
+			Level string `env:"LEVEL" oneof:"debug info" validate:"nonsense"`
+		} `envPrefix:"BLOCK_"`
+	}
+	err := Load(&unset, WithSources(MapSource(nil)))
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 3 || !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected 3 validation errors, got %v", err)
+	}
+	if fe := errs.Errs[2]; fe.Key != "BLOCK_LEVEL" || !strings.Contains(fe.Error(), `unknown validate rule "nonsense"`) {
+		t.Errorf("expected the unknown rule of BLOCK_LEVEL, got %v", fe)
+	}
+}
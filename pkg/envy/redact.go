@@ -0,0 +1,211 @@
+package envy
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// mask replaces secret values in Dump and Redacted.
+const mask = "*****"
+
+// redactedField is a leaf of a config struct ready to be printed.
+type redactedField struct {
+	Path  string
+	Key   string // Empty for nil nested struct pointers
+	Value any
+}
+
+// Redacted returns target, a pointer to a config struct, as a nested map
+// keyed by field name, safe to log. Fields tagged `secret:"true"` are
+// masked, passwords in URLs are masked everywhere. Fields the Report of
+// the load, passed with WithReport, marks as resolved or decrypted are
+// masked too. Returns nil when target isn't a pointer to a struct.
+func Redacted(target any, opts ...Option) map[string]any {
+	val := reflect.ValueOf(target)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	pl := planFor(val.Elem().Type())
+	out := map[string]any{}
+	for _, f := range redactStruct(val.Elem(), pl.fields, secretPaths(opts)) {
+		m := out
+		parts := strings.Split(f.Path, ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				m[part] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = f.Value
+	}
+	return out
+}
+
+// Dump writes target, a pointer to a config struct, as an aligned table of
+// field path, env key and value, with the same masking and options as
+// Redacted.
+func Dump(target any, w io.Writer, opts ...Option) error {
+	val := reflect.ValueOf(target)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("target must be a pointer to a struct")
+	}
+
+	pl := planFor(val.Elem().Type())
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, f := range redactStruct(val.Elem(), pl.fields, secretPaths(opts)) {
+		key, value := f.Key, f.Value
+		if key == "" {
+			key, value = "-", "<nil>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%v\n", f.Path, key, value)
+	}
+	return tw.Flush()
+}
+
+// secretPaths returns the paths of the fields the report passed with
+// WithReport marks as resolved or decrypted.
+func secretPaths(opts []Option) map[string]bool {
+	secrets := map[string]bool{}
+	if report := newOptions(opts).report; report != nil {
+		for _, f := range report.Fields {
+			if f.Resolved || f.Decrypted {
+				secrets[f.Field] = true
+			}
+		}
+	}
+	return secrets
+}
+
+// redactStruct lists the leaves of val with an env tag, following their
+// plans like parseStruct. Fields at the paths in secrets are masked like
+// tagged ones.
+func redactStruct(val reflect.Value, fields []fieldPlan, secrets map[string]bool) []redactedField {
+	var out []redactedField
+	for i := range fields {
+		fp := &fields[i]
+		field := val.Field(fp.index)
+
+		if fp.nested != nil {
+			if fp.ptr {
+				if field.IsNil() {
+					out = append(out, redactedField{Path: fp.path})
+					continue
+				}
+				field = field.Elem()
+			}
+			out = append(out, redactStruct(field, fp.nested, secrets)...)
+			continue
+		}
+
+		out = append(out, redactedField{
+			Path:  fp.path,
+			Key:   fp.key,
+			Value: redactValue(field, fp, fp.secret || secrets[fp.path]),
+		})
+	}
+	return out
+}
+
+// redactValue turns a field value into something printable, masking it
+// entirely when secret and masking URL passwords either way.
+func redactValue(v reflect.Value, fp *fieldPlan, secret bool) any {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		return redactString(v.String(), secret)
+	case isContainer(v.Kind()) && !isValueType(v.Type()):
+		if secret && v.Len() > 0 {
+			return mask
+		}
+		if v.Kind() == reflect.Map {
+			m := make(map[string]any, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				m[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value(), fp, false)
+			}
+			return m
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i), fp, false)
+		}
+		return items
+	case secret:
+		if v.IsZero() {
+			return ""
+		}
+		return mask
+	}
+	return printable(v, fp)
+}
+
+// printable formats types whose Go value reads poorly in logs.
+func printable(v reflect.Value, fp *fieldPlan) any {
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case timeType:
+		layout := fp.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return v.Interface().(time.Time).Format(layout)
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return v.Interface()
+}
+
+// redactString masks secrets. URLs keep their scheme, host and path so the
+// log still tells where a service connects to.
+func redactString(s string, secret bool) string {
+	u, err := url.Parse(s)
+	isURL := err == nil && u.Scheme != "" && u.Host != ""
+
+	switch {
+	case isURL && secret:
+		userinfo := ""
+		if u.User != nil {
+			userinfo = mask
+		}
+		if u.RawQuery != "" {
+			u.RawQuery = mask
+		}
+		return withUserinfo(u, userinfo)
+	case isURL:
+		if _, ok := u.User.Password(); ok {
+			return withUserinfo(u, url.User(u.User.Username()).String()+":"+mask)
+		}
+		return s
+	case secret && s != "":
+		return mask
+	}
+	return s
+}
+
+// withUserinfo formats u with a raw userinfo, url.URL would escape the
+// mask characters.
+func withUserinfo(u *url.URL, userinfo string) string {
+	u.User = nil
+	if userinfo == "" {
+		return u.String()
+	}
+	return strings.Replace(u.String(), "://", "://"+userinfo+"@", 1)
+}
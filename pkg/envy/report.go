@@ -0,0 +1,121 @@
+package envy
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Kinds of Origin.Source. Custom sources are reported by their type name.
+const (
+	SourceEnv     = "env"     // The process environment
+	SourceDotenv  = "dotenv"  // A .env file, see Origin.File and Origin.Line
+	SourceFile    = "file"    // A secret file named by a _FILE variable
+	SourceMap     = "map"     // A MapSource
+	SourceDefault = "default" // The default tag
+)
+
+// Origin tells where a value was read from. The zero Origin means the
+// value wasn't set at all.
+type Origin struct {
+	Source string
+	File   string // The .env or secret file, if any
+	Line   int    // Line in the .env file, 0 when unknown
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Source == "":
+		return "unset"
+	case o.Source == SourceDotenv && o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.File, o.Line)
+	case o.File != "":
+		return o.File
+	}
+	return o.Source
+}
+
+// FieldReport is the provenance of a single field.
+type FieldReport struct {
+	Field        string // Struct field path, e.g. "Database.DSN"
+	Key          string // Env key, including any envPrefix
+	Value        string // Value as shown by Dump, secrets masked
+	Origin       Origin
+	Default      bool // The default tag was used
+	Interpolated bool // ${VAR} references were expanded
+	Resolved     bool // A ref+scheme:// secret reference was resolved
+	Decrypted    bool // An ENC[...] value was decrypted
+}
+
+// Report tells where every field of a loaded config got its value. Pass
+// one to Load with WithReport.
+type Report struct {
+	Files  []string // .env files read, lowest precedence first
+	Fields []FieldReport
+}
+
+// WithReport fills r with the provenance of every field on Load, even when
+// Load returns an error.
+func WithReport(r *Report) Option {
+	return func(o *options) {
+		o.report = r
+	}
+}
+
+// Field returns the report of the field at path, e.g. "Database.DSN".
+func (r *Report) Field(path string) (FieldReport, bool) {
+	for _, f := range r.Fields {
+		if f.Field == path {
+			return f, true
+		}
+	}
+	return FieldReport{}, false
+}
+
+// WriteTo prints the report as an aligned table, for a --print-config
+// style debug command.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if len(r.Files) > 0 {
+		fmt.Fprintf(&b, "Files: %s\n\n", strings.Join(r.Files, ", "))
+	}
+
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tKEY\tVALUE\tSOURCE")
+	for _, f := range r.Fields {
+		source := f.Origin.String()
+		if f.Interpolated {
+			source += " (interpolated)"
+		}
+		if f.Resolved {
+			source += " (resolved)"
+		}
+		if f.Decrypted {
+			source += " (decrypted)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Field, f.Key, f.Value, source)
+	}
+	tw.Flush()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	r.WriteTo(&b)
+	return b.String()
+}
+
+// record adds the report of a field once it has been parsed.
+func (p *parser) record(field reflect.Value, fp *fieldPlan, rep FieldReport) {
+	if p.opts.report == nil {
+		return
+	}
+	if v := redactValue(field, fp, fp.secret || rep.Resolved || rep.Decrypted); v != nil {
+		rep.Value = fmt.Sprint(v)
+	}
+	p.fields = append(p.fields, rep)
+}
@@ -0,0 +1,110 @@
+package envy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type reportedConfig struct {
+	Port     int    `env:"R_PORT" default:"8080"`
+	Host     string `env:"R_HOST"`
+	Level    string `env:"R_LEVEL"`
+	URL      string `env:"R_URL" default:"http://${R_HOST}:${R_PORT}"`
+	Password string `env:"R_PASSWORD" file:"allow" secret:"true"`
+	Missing  string `env:"R_MISSING"`
+}
+
+func TestLoad_Report(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("# comment\nR_HOST=localhost\nR_LEVEL=info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env.local"), []byte("R_LEVEL=debug\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "password")
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("R_PASSWORD_FILE", secret)
+	t.Setenv("R_PORT", "9090")
+
+	for _, mode := range []struct {
+		name string
+		opts []Option
+	}{
+		{"overlay", []Option{WithOverlay()}},
+		{"export", nil},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				os.Unsetenv("R_HOST")
+				os.Unsetenv("R_LEVEL")
+			})
+
+			var rep Report
+			var cfg reportedConfig
+			opts := append([]Option{WithEnvDir(dir), WithReport(&rep)}, mode.opts...)
+			if err := Load(&cfg, opts...); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			expectedFiles := []string{filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local")}
+			if strings.Join(rep.Files, ",") != strings.Join(expectedFiles, ",") {
+				t.Errorf("expected files %v, got %v", expectedFiles, rep.Files)
+			}
+
+			expected := map[string]string{
+				"Port":     "env",
+				"Host":     filepath.Join(dir, ".env") + ":2",
+				"Level":    filepath.Join(dir, ".env.local") + ":1",
+				"URL":      "default (interpolated)",
+				"Password": secret,
+				"Missing":  "unset",
+			}
+			for field, source := range expected {
+				f, ok := rep.Field(field)
+				if !ok {
+					t.Errorf("missing report for %s", field)
+					continue
+				}
+				got := f.Origin.String()
+				if f.Interpolated {
+					got += " (interpolated)"
+				}
+				if got != source {
+					t.Errorf("%s: expected source %q, got %q", field, source, got)
+				}
+			}
+
+			if f, _ := rep.Field("URL"); !f.Default || f.Value != "http://localhost:9090" {
+				t.Errorf("unexpected url report %+v", f)
+			}
+			if f, _ := rep.Field("Password"); f.Value != mask {
+				t.Errorf("expected masked password, got %q", f.Value)
+			}
+
+			out := rep.String()
+			if !strings.Contains(out, "R_URL") || strings.Contains(out, "hunter2") {
+				t.Errorf("unexpected report output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestLoad_ReportSources(t *testing.T) {
+	var rep Report
+	var cfg reportedConfig
+	err := Load(&cfg, WithReport(&rep), WithSources(MapSource(map[string]string{"R_HOST": "db"})))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if f, _ := rep.Field("Host"); f.Origin.Source != SourceMap {
+		t.Errorf("expected map source, got %+v", f.Origin)
+	}
+	if len(rep.Files) != 0 {
+		t.Errorf("expected no files with explicit sources, got %v", rep.Files)
+	}
+}
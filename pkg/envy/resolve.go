@@ -0,0 +1,141 @@
+package envy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refPrefix starts a secret reference, ref+<scheme>://<reference>.
+const refPrefix = "ref+"
+
+// defaultResolveTimeout bounds each reference resolution unless
+// WithResolveTimeout says otherwise.
+const defaultResolveTimeout = 30 * time.Second
+
+// Resolver fetches the secret a reference points to. For
+// ref+vault://db/creds#password, ref is "db/creds#password".
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{
+		"file": ResolverFunc(resolveFile),
+	}
+)
+
+// ExecResolver runs a command and returns its output, ref+exec://pass show
+// db. The command is split on spaces, without a shell. It isn't registered
+// by default: anyone who can set a variable, a .env file or a default could
+// run commands with it, so register it only when all of them are trusted:
+//
+//	envy.RegisterResolver("exec", envy.ExecResolver)
+var ExecResolver Resolver = ResolverFunc(resolveExec)
+
+// RegisterResolver makes values of the form ref+<scheme>://... resolve
+// through r, for example to read a team's secret store. The file scheme is
+// built in and can be replaced. Registering a nil resolver removes the
+// scheme.
+func RegisterResolver(scheme string, r Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+
+	if r == nil {
+		delete(resolvers, scheme)
+		return
+	}
+	resolvers[scheme] = r
+}
+
+func lookupResolver(scheme string) (Resolver, bool) {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+
+	r, ok := resolvers[scheme]
+	return r, ok
+}
+
+// WithContext sets the context passed to resolvers, cancelling it or its
+// deadline aborts the load. context.Background() by default.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithResolveTimeout limits how long each reference may take to resolve,
+// 30 seconds by default.
+func WithResolveTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.resolveTimeout = d
+	}
+}
+
+// resolveRef resolves value when it is a secret reference, reporting
+// whether it was one.
+func (p *parser) resolveRef(value string) (string, bool, error) {
+	rest, ok := strings.CutPrefix(value, refPrefix)
+	if !ok {
+		return value, false, nil
+	}
+	scheme, ref, ok := strings.Cut(rest, "://")
+	if !ok || scheme == "" {
+		return "", true, fmt.Errorf("invalid reference, expected %s<scheme>://...", refPrefix)
+	}
+	r, ok := lookupResolver(scheme)
+	if !ok {
+		return "", true, fmt.Errorf("no resolver registered for %s%s://", refPrefix, scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(p.opts.ctx, p.opts.resolveTimeout)
+	defer cancel()
+	resolved, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return "", true, fmt.Errorf("resolving %s reference: %w", scheme, err)
+	}
+	return resolved, true, nil
+}
+
+// resolveFile reads a file, ref+file:///run/secrets/db.
+func resolveFile(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(data)), nil
+}
+
+// resolveExec implements ExecResolver.
+func resolveExec(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	return trimNewline(string(out)), nil
+}
@@ -0,0 +1,226 @@
+package envy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type resolvedConfig struct {
+	Password string `env:"RS_PASSWORD"`
+	Token    string `env:"RS_TOKEN"`
+	Port     int    `env:"RS_PORT"`
+	Plain    string `env:"RS_PLAIN"`
+}
+
+func TestLoad_ResolveReferences(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "password"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	RegisterResolver("test", ResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return map[string]string{"port": "5432"}[ref], nil
+	}))
+	defer RegisterResolver("test", nil)
+	RegisterResolver("exec", ExecResolver)
+	defer RegisterResolver("exec", nil)
+
+	var cfg resolvedConfig
+	var report Report
+	err := Load(&cfg, WithReport(&report), WithSources(MapSource(map[string]string{
+		"RS_DIR":      dir,
+		"RS_PASSWORD": "ref+file://${RS_DIR}/password",
+		"RS_TOKEN":    "ref+exec://echo s3cr3t-token",
+		"RS_PORT":     "ref+test://port",
+		"RS_PLAIN":    "visible",
+	})))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Password != "hunter2" || cfg.Token != "s3cr3t-token" || cfg.Port != 5432 {
+		t.Errorf("expected resolved values, got %+v", cfg)
+	}
+
+	// Resolved values are secrets wherever the config is printed with the
+	// report of the load
+	var dump strings.Builder
+	if err := Dump(&cfg, &dump, WithReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "s3cr3t-token", "5432"} {
+		if strings.Contains(dump.String(), secret) || strings.Contains(report.String(), secret) {
+			t.Errorf("expected %s to be masked, got:\n%s\n%s", secret, dump.String(), report.String())
+		}
+	}
+	if !strings.Contains(dump.String(), "visible") {
+		t.Errorf("expected the plain value to be shown, got:\n%s", dump.String())
+	}
+	if f, _ := report.Field("Password"); !f.Resolved || !f.Interpolated || f.Value != mask {
+		t.Errorf("expected a resolved, interpolated and masked Password, got %+v", f)
+	}
+	if redacted := Redacted(&cfg, WithReport(&report)); redacted["Token"] != mask {
+		t.Errorf("expected Token to be masked, got %v", redacted["Token"])
+	}
+
+	// Other configs aren't masked by earlier loads
+	other := resolvedConfig{Plain: "hunter2", Port: 5432}
+	if redacted := Redacted(&other); redacted["Plain"] != "hunter2" || redacted["Port"] != 5432 {
+		t.Errorf("expected unrelated values to be shown, got %v", redacted)
+	}
+}
+
+func TestDump_ResolvedWithReport(t *testing.T) {
+	type config struct {
+		Password string `env:"RO_PASSWORD"`
+		Plain    string `env:"RO_PLAIN"`
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	if err := os.WriteFile(path, []byte("hunter2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("RO_PASSWORD=ref+file://"+path+"\nRO_PLAIN=visible\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The report is enough to mask the copy Parse returns, the options
+	// reading the overlay needn't be repeated
+	var report Report
+	cfg, err := Parse[config](WithEnvDir(dir), WithOverlay(), WithReport(&report))
+	if err != nil || cfg.Password != "hunter2" {
+		t.Fatalf("expected the resolved password, got %+v, %v", cfg, err)
+	}
+	var dump strings.Builder
+	if err := Dump(&cfg, &dump, WithReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump.String(), "hunter2") || !strings.Contains(dump.String(), "visible") {
+		t.Errorf("expected only the resolved password to be masked, got:\n%s", dump.String())
+	}
+}
+
+func TestDump_ResolvedFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("hunter2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RS_PASSWORD", "ref+file://"+path)
+	t.Setenv("RS_PLAIN", "visible")
+
+	var cfg resolvedConfig
+	var report Report
+	if err := Load(&cfg, WithReport(&report)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var dump strings.Builder
+	if err := Dump(&cfg, &dump, WithReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump.String(), "hunter2") || !strings.Contains(dump.String(), "visible") {
+		t.Errorf("expected only the resolved password to be masked, got:\n%s", dump.String())
+	}
+}
+
+func TestLoad_ReferencesToSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"password": "hunter2\n", "user": "app\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := testKey(t)
+	host, _ := Encrypt(key, "RF_HOST", "db")
+
+	var cfg struct {
+		User  string `env:"RF_USER" file:"allow"`
+		DSN   string `env:"RF_DSN" default:"postgres://${RF_USER}:${RF_PASS}@${RF_HOST}/app"`
+		Plain string `env:"RF_PLAIN" default:"${RF_USER}"`
+	}
+	var report Report
+	err := Load(&cfg, WithReport(&report), WithSources(MapSource(map[string]string{
+		"ENVY_KEY":     key,
+		"RF_USER_FILE": filepath.Join(dir, "user"),
+		"RF_PASS":      "ref+file://" + filepath.Join(dir, "password"),
+		"RF_HOST":      host,
+	})))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.DSN != "postgres://app:hunter2@db/app" || cfg.Plain != "app" {
+		t.Errorf("expected references read like fields, got %+v", cfg)
+	}
+	if f, _ := report.Field("DSN"); !f.Resolved || !f.Decrypted || strings.Contains(f.Value, "hunter2") {
+		t.Errorf("expected DSN to be a resolved and decrypted secret, got %+v", f)
+	}
+	if f, _ := report.Field("Plain"); f.Resolved || f.Decrypted {
+		t.Errorf("expected Plain not to be a secret, got %+v", f)
+	}
+
+	err = Load(&cfg, WithSources(MapSource(map[string]string{"RF_PASS": "ref+file:///does/not/exist"})))
+	if err == nil || !strings.Contains(err.Error(), "var `RF_DSN` (DSN): parse error: RF_PASS: resolving file reference") {
+		t.Errorf("expected the reference error to name RF_PASS, got %v", err)
+	}
+}
+
+func TestLoad_ResolveErrors(t *testing.T) {
+	RegisterResolver("slow", ResolverFunc(func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	RegisterResolver("bad", ResolverFunc(func(context.Context, string) (string, error) {
+		return "not-a-number", nil
+	}))
+	defer RegisterResolver("slow", nil)
+	defer RegisterResolver("bad", nil)
+
+	var cfg resolvedConfig
+	err := Load(&cfg, WithResolveTimeout(10*time.Millisecond), WithSources(MapSource(map[string]string{
+		"RS_PASSWORD": "ref+nope://x",
+		"RS_TOKEN":    "ref+slow://x",
+		"RS_PORT":     "ref+bad://x",
+		"RS_PLAIN":    "ref+file:///does/not/exist",
+	})))
+
+	var errs *Errors
+	if !errors.As(err, &errs) || len(errs.Errs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", err)
+	}
+	for i, want := range []string{
+		"var `RS_PASSWORD` (Password): parse error: no resolver registered for ref+nope://",
+		"var `RS_TOKEN` (Token): parse error: resolving slow reference: context deadline exceeded",
+		"var `RS_PORT` (Port): parse error: secret value is not a valid int",
+		"var `RS_PLAIN` (Plain): parse error: resolving file reference: open /does/not/exist: no such file or directory",
+	} {
+		if got := errs.Errs[i].Error(); got != want {
+			t.Errorf("expected error %d to be %q, got %q", i, want, got)
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected the timeout to match context.DeadlineExceeded")
+	}
+	if errs.Errs[2].Value != mask {
+		t.Errorf("expected the resolved value to be masked, got %q", errs.Errs[2].Value)
+	}
+
+	// The caller's context cancels resolution too
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Load(&cfg, WithContext(ctx), WithSources(MapSource(map[string]string{"RS_TOKEN": "ref+slow://x"})))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// exec runs commands and has to be registered explicitly
+	err = Load(&cfg, WithSources(MapSource(map[string]string{"RS_TOKEN": "ref+exec://echo x"})))
+	if err == nil || !strings.Contains(err.Error(), "no resolver registered for ref+exec://") {
+		t.Errorf("expected exec to be unregistered by default, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package envy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fileSuffix is appended to an env key to name a file holding its value,
+// the convention used by Docker and Kubernetes secrets.
+const fileSuffix = "_FILE"
+
+// lookupFile resolves key from the file named by key_FILE when the field
+// allows it. It reports found=false when no key_FILE variable is set, so
+// the caller falls back to the plain variable.
+func (p *parser) lookupFile(key string, allowed bool) (value string, found bool, err error) {
+	if !p.opts.fileSecrets && !allowed {
+		return "", false, nil
+	}
+
+	path, ok := p.lookupValue(key + fileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	if _, direct := p.lookupValue(key); direct {
+		return "", true, fmt.Errorf("both %s and %s%s are set", key, key, fileSuffix)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", true, fmt.Errorf("reading %s%s: %w", key, fileSuffix, err)
+	}
+
+	return trimNewline(string(data)), true, nil
+}
+
+// trimNewline drops the newline secret files and command output usually
+// end with, added by the editor or echo.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
@@ -0,0 +1,72 @@
+package envy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSecret(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	t.Setenv("S_API_KEY_FILE", writeSecret(t, "s3cr3t\n"))
+	t.Setenv("S_DB_PASS_FILE", writeSecret(t, "hunter2\r\n"))
+	t.Setenv("S_PLAIN_FILE", writeSecret(t, "ignored"))
+
+	var cfg struct {
+		APIKey string `env:"S_API_KEY" file:"allow" required:"true"`
+		DBPass string `env:"S_DB_PASS" file:"allow"`
+		Plain  string `env:"S_PLAIN" default:"fallback"`
+		Other  string `env:"S_OTHER" file:"allow" default:"dflt"`
+	}
+	if err := parse(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.APIKey != "s3cr3t" {
+		t.Errorf("expected api key from file, got %q", cfg.APIKey)
+	}
+	if cfg.DBPass != "hunter2" {
+		t.Errorf("expected db pass from file, got %q", cfg.DBPass)
+	}
+	if cfg.Plain != "fallback" {
+		t.Errorf("expected field without file tag to ignore _FILE, got %q", cfg.Plain)
+	}
+	if cfg.Other != "dflt" {
+		t.Errorf("expected default without _FILE, got %q", cfg.Other)
+	}
+
+	// The option turns it on for every field
+	if err := parse(&cfg, WithFileSecrets()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Plain != "ignored" {
+		t.Errorf("expected plain from file with WithFileSecrets, got %q", cfg.Plain)
+	}
+}
+
+func TestLoad_SecretFileConflicts(t *testing.T) {
+	t.Setenv("S_API_KEY", "direct")
+	t.Setenv("S_API_KEY_FILE", writeSecret(t, "from-file"))
+	t.Setenv("S_MISSING_FILE", filepath.Join(t.TempDir(), "nope"))
+
+	var cfg struct {
+		APIKey  string `env:"S_API_KEY"`
+		Missing string `env:"S_MISSING"`
+	}
+	err := parse(&cfg, WithFileSecrets())
+	errs, ok := err.(*Errors)
+	if !ok || len(errs.Errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	if errs.Errs[0].Key != "S_API_KEY" || errs.Errs[1].Key != "S_MISSING" {
+		t.Errorf("unexpected errors %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package envy
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Source is a place env values are read from. Lookup reports whether the
+// key is present, an empty value still counts as present.
+type Source interface {
+	Lookup(key string) (string, bool)
+}
+
+type envSource struct{}
+
+func (envSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// EnvSource reads the process environment.
+func EnvSource() Source {
+	return envSource{}
+}
+
+type mapSource map[string]string
+
+func (m mapSource) Lookup(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+// MapSource reads an in-memory map, handy for tests and embedded tools.
+func MapSource(values map[string]string) Source {
+	return mapSource(values)
+}
+
+// dotenvSource holds the values of one or more .env files along with the
+// file and line each value came from.
+type dotenvSource struct {
+	values  map[string]string
+	origins map[string]Origin
+}
+
+func (s *dotenvSource) Lookup(key string) (string, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// merge adds the values of other, overriding existing keys.
+func (s *dotenvSource) merge(other *dotenvSource) {
+	for k, v := range other.values {
+		s.values[k] = v
+		s.origins[k] = other.origins[k]
+	}
+}
+
+// newDotenvSource parses .env content read from name.
+func newDotenvSource(name string, data []byte, native bool) (*dotenvSource, error) {
+	parse := parseEnvFile
+	if native {
+		parse = parseDotenv
+	}
+
+	values, err := parse(bytes.NewReader(data))
+	if derr, ok := err.(*DotenvError); ok {
+		derr.File = name
+		return nil, derr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	s := &dotenvSource{values: values, origins: make(map[string]Origin, len(values))}
+	lines := dotenvLines(data)
+	for k := range values {
+		s.origins[k] = Origin{Source: SourceDotenv, File: name, Line: lines[k]}
+	}
+	return s, nil
+}
+
+// DotenvSource reads a .env file once, without exporting its values to the
+// process environment.
+func DotenvSource(path string) (Source, error) {
+	src, err := newOptions(nil).readEnvFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// FSSource reads a .env file from fsys, for example one embedded with
+// go:embed.
+func FSSource(fsys fs.FS, name string) (Source, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	src, err := newDotenvSource(name, data, false)
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// lookupSources asks each source in order, the first one with the key
+// wins. It also reports the source that answered.
+func lookupSources(sources []Source, key string) (string, Source, bool) {
+	for _, s := range sources {
+		if v, ok := s.Lookup(key); ok {
+			return v, s, true
+		}
+	}
+	return "", nil, false
+}
+
+// exported remembers the .env values envy exported into the process
+// environment, so reports can still tell they came from a file and loads
+// expand them as written.
+var exported sync.Map // key -> exportedValue
+
+type exportedValue struct {
+	value  string // As exported, references expanded
+	raw    string // As written in the file
+	origin Origin
+}
+
+// exportedAs returns what envy exported as key, as long as the process
+// environment still holds value.
+func exportedAs(key, value string) (exportedValue, bool) {
+	e, ok := exported.Load(key)
+	if !ok || e.(exportedValue).value != value {
+		return exportedValue{}, false
+	}
+	return e.(exportedValue), true
+}
+
+// originOf tells where src found key.
+func originOf(src Source, key string) Origin {
+	switch s := src.(type) {
+	case envSource:
+		v, _ := os.LookupEnv(key)
+		if e, ok := exportedAs(key, v); ok {
+			return e.origin
+		}
+		return Origin{Source: SourceEnv}
+	case *dotenvSource:
+		return s.origins[key]
+	case mapSource:
+		return Origin{Source: SourceMap}
+	}
+	return Origin{Source: fmt.Sprintf("%T", src)}
+}
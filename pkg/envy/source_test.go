@@ -0,0 +1,87 @@
+package envy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+type sourcedConfig struct {
+	Port  int    `env:"SRC_PORT" default:"8080"`
+	Name  string `env:"SRC_NAME"`
+	Debug bool   `env:"SRC_DEBUG"`
+}
+
+func TestLoader_Sources(t *testing.T) {
+	t.Setenv("SRC_NAME", "from-process")
+
+	path := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(path, []byte("SRC_PORT=7070\nSRC_DEBUG=true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dotenv, err := DotenvSource(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	loader := New(WithSources(MapSource(map[string]string{"SRC_PORT": "9090"}), dotenv))
+
+	var cfg sourcedConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("expected the first source to win, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Error("expected debug from the dotenv source")
+	}
+	if cfg.Name != "" {
+		t.Errorf("expected process env to be ignored with explicit sources, got %q", cfg.Name)
+	}
+	if _, ok := os.LookupEnv("SRC_DEBUG"); ok {
+		t.Error("expected dotenv source not to export values")
+	}
+}
+
+func TestFSSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/.env": {Data: []byte("SRC_NAME=embedded\n")},
+	}
+	src, err := FSSource(fsys, "config/.env")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var cfg sourcedConfig
+	if err := Load(&cfg, WithSources(src, EnvSource())); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Name != "embedded" || cfg.Port != 8080 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	if src, err := FSSource(fsys, "missing.env"); err == nil || src != nil {
+		t.Errorf("expected a nil source and an error for a missing file, got %v, %v", src, err)
+	}
+	fsys["bad.env"] = &fstest.MapFile{Data: []byte("SRC_NAME=\"oops\n")}
+	if src, err := FSSource(fsys, "bad.env"); err == nil || src != nil {
+		t.Errorf("expected a nil source and an error for a bad file, got %v, %v", src, err)
+	}
+	if src, err := DotenvSource(filepath.Join(t.TempDir(), "missing.env")); err == nil || src != nil {
+		t.Errorf("expected a nil source and an error for a missing file, got %v, %v", src, err)
+	}
+}
+
+func TestFSSource_SingleQuotedDollars(t *testing.T) {
+	// Whether Q_A is literal can't be told from a file the built-in parser
+	// rejects, godotenv alone would accept Q_B
+	fsys := fstest.MapFS{".env": {Data: []byte("Q_A='$Q_HOME'\nQ_B: x\n")}}
+	_, err := FSSource(fsys, ".env")
+	var derr *DotenvError
+	if !errors.As(err, &derr) || derr.File != ".env" || derr.Line != 2 {
+		t.Errorf("expected a syntax error on .env:2, got %v", err)
+	}
+}
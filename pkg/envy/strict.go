@@ -0,0 +1,72 @@
+package envy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithStrict fails loading when a variable starting with one of the
+// prefixes isn't read by any field, so a typo like APP_PROT=9090 doesn't
+// silently leave the default port. Both the environment and the loaded
+// .env files are checked, each unknown key is reported as a FieldError
+// matching ErrUnknown, with a suggestion when a known key is close.
+func WithStrict(prefixes ...string) Option {
+	return func(o *options) {
+		o.strict = append(o.strict, prefixes...)
+	}
+}
+
+// checkStrict records an error for every unknown key under the
+// WithStrict prefixes.
+func (p *parser) checkStrict(pl *plan) {
+	unknown := p.unknownKeys(p.opts.strict)
+	if len(unknown) == 0 {
+		return
+	}
+
+	var known []string
+	walkPlan(pl.fields, func(fp *fieldPlan) {
+		known = append(known, fp.key)
+	})
+	for _, key := range unknown {
+		err := ErrUnknown
+		if s := suggest(key, known); s != "" {
+			err = fmt.Errorf("%w, did you mean %s?", ErrUnknown, s)
+		}
+		p.errs = append(p.errs, &FieldError{Key: key, Err: err})
+	}
+}
+
+// suggest returns the known key closest to key, if it is close enough to
+// be a typo: at most a third of the characters differ, and at least two
+// edits are always tolerated.
+func suggest(key string, known []string) string {
+	best, bestDist := "", max(2, len(key)/3)+1
+	for _, k := range known {
+		if d := editDistance(strings.ToUpper(key), strings.ToUpper(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,117 @@
+package envy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type strictConfig struct {
+	Port     int    `env:"APP_PORT" default:"8080"`
+	Host     string `env:"APP_HOST"`
+	Password string `env:"APP_PASSWORD" file:"allow"`
+	DB       struct {
+		Name string `env:"NAME"`
+	} `envPrefix:"DB_"`
+}
+
+func TestLoad_Strict(t *testing.T) {
+	var cfg strictConfig
+	err := Load(&cfg, WithStrict("APP_", "DB_"), WithSources(MapSource(map[string]string{
+		"APP_PROT":       "9090",
+		"APP_HOST":       "localhost",
+		"APP_TOTALLY":    "x",
+		"DB_NAEM":        "app",
+		"APP_PASSWORD":   "",
+		"OTHER_SETTING":  "ignored",
+		"APP_URL":        "${APP_HOST}",
+		"APP_HOSTNAME_X": "x",
+	})))
+
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected *Errors, got %v", err)
+	}
+	var got []string
+	for _, e := range errs.Errs {
+		if !errors.Is(e, ErrUnknown) {
+			t.Errorf("expected ErrUnknown, got %v", e)
+		}
+		got = append(got, e.Error())
+	}
+	want := []string{
+		"var `APP_HOSTNAME_X`: unknown variable",
+		"var `APP_PROT`: unknown variable, did you mean APP_PORT?",
+		"var `APP_TOTALLY`: unknown variable",
+		"var `APP_URL`: unknown variable",
+		"var `DB_NAEM`: unknown variable, did you mean DB_NAME?",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected errors:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if cfg.Port != 8080 || cfg.Host != "localhost" {
+		t.Errorf("expected fields to still load in strict mode, got %+v", cfg)
+	}
+}
+
+func TestLoad_StrictEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("APP_HOST=localhost\nAPP_PASWORD=x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_PORT", "9090")
+
+	var cfg strictConfig
+	err := Load(&cfg, WithEnvDir(dir), WithOverlay(), WithStrict("APP_"))
+	if !errors.Is(err, ErrUnknown) || !strings.Contains(err.Error(), "APP_PASWORD`: unknown variable, did you mean APP_PASSWORD?") {
+		t.Errorf("expected APP_PASWORD to be unknown, got %v", err)
+	}
+
+	// Keys read by a field are known, including the _FILE form
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("APP_HOST=localhost\nAPP_PASSWORD_FILE="+secret+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(&cfg, WithEnvDir(dir), WithOverlay(), WithStrict("APP_")); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestLoad_StrictControlVariables(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env.production"), []byte("APP_HOST=prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("APP_KEY", "unused")
+
+	// Neither the environment selector nor the key variable are unknown
+	var cfg strictConfig
+	if err := Load(&cfg, WithEnvDir(dir), WithOverlay(), WithKeyEnv("APP_KEY"), WithStrict("APP_")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Host != "prod" {
+		t.Errorf("expected the production file to load, got %q", cfg.Host)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	known := []string{"APP_PORT", "APP_HOST", "DB_URL"}
+	for key, want := range map[string]string{
+		"APP_PROT":  "APP_PORT",
+		"APP_HOTS":  "APP_HOST",
+		"app_port":  "APP_PORT",
+		"DB_URI":    "DB_URL",
+		"APP_DEBUG": "",
+		"DB_X":      "",
+	} {
+		if got := suggest(key, known); got != want {
+			t.Errorf("expected %q for %q, got %q", want, key, got)
+		}
+	}
+}
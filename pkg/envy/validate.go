@@ -0,0 +1,236 @@
+package envy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validators are the built-in checks usable in the `validate` tag, run
+// against the string form of the value (or of each element for slices).
+var validators = map[string]func(string) error{
+	"url": func(s string) error {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+		return nil
+	},
+	"hostport": func(s string) error {
+		_, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return errors.New("must be in host:port form")
+		}
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+			return fmt.Errorf("port %q must be between 1 and 65535", port)
+		}
+		return nil
+	},
+	"email": func(s string) error {
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return errors.New("must be an email address")
+		}
+		return nil
+	},
+	"nonempty": func(s string) error {
+		if s == "" {
+			return errors.New("must not be empty")
+		}
+		return nil
+	},
+}
+
+// hasRule reports whether the `validate` tag lists the named check.
+func hasRule(structField reflect.StructField, name string) bool {
+	for _, rule := range strings.Split(structField.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeBound is a compiled min or max tag.
+type rangeBound struct {
+	min   bool
+	limit string // As written in the tag, for messages
+	// compare returns the sign of v minus the limit and v as shown in
+	// messages
+	compare func(v reflect.Value) (int, string)
+}
+
+// compileValidation reads the validation tags of fp once, for validate.
+// Invalid tags are kept in fp.tagErr and reported by every load, whether
+// the field is set or not.
+func (fp *fieldPlan) compileValidation(structField reflect.StructField) {
+	t := structField.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, name := range []string{"min", "max"} {
+		limit := structField.Tag.Get(name)
+		if limit == "" {
+			continue
+		}
+		bound, err := compileBound(t, name, limit)
+		if err != nil {
+			fp.tagErr = err
+			return
+		}
+		fp.bounds = append(fp.bounds, bound)
+	}
+
+	fp.oneOf = strings.Fields(structField.Tag.Get("oneof"))
+
+	if pattern := structField.Tag.Get("pattern"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fp.tagErr = fmt.Errorf("invalid pattern tag: %w", err)
+			return
+		}
+		fp.pattern = re
+	}
+
+	if rules := structField.Tag.Get("validate"); rules != "" {
+		for _, rule := range strings.Split(rules, ",") {
+			rule = strings.TrimSpace(rule)
+			check, ok := validators[rule]
+			if !ok {
+				fp.tagErr = fmt.Errorf("unknown validate rule %q", rule)
+				return
+			}
+			fp.rules = append(fp.rules, check)
+		}
+	}
+}
+
+// compileBound parses the limit of a min or max tag for values of type t:
+// numbers are compared by value (durations as durations), strings, slices
+// and maps by length.
+func compileBound(t reflect.Type, name, limit string) (rangeBound, error) {
+	bound := rangeBound{min: name == "min", limit: limit}
+	var err error
+	switch k := t.Kind(); {
+	case t == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(limit)
+		bound.compare = func(v reflect.Value) (int, string) {
+			return compare(v.Int(), int64(d)), time.Duration(v.Int()).String()
+		}
+	case k >= reflect.Int && k <= reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(limit, 10, 64)
+		bound.compare = func(v reflect.Value) (int, string) {
+			return compare(v.Int(), n), strconv.FormatInt(v.Int(), 10)
+		}
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		var n uint64
+		n, err = strconv.ParseUint(limit, 10, 64)
+		bound.compare = func(v reflect.Value) (int, string) {
+			return compare(v.Uint(), n), strconv.FormatUint(v.Uint(), 10)
+		}
+	case k == reflect.Float32 || k == reflect.Float64:
+		var n float64
+		n, err = strconv.ParseFloat(limit, 64)
+		bound.compare = func(v reflect.Value) (int, string) {
+			return compare(v.Float(), n), strconv.FormatFloat(v.Float(), 'g', -1, 64)
+		}
+	case k == reflect.String || k == reflect.Slice || k == reflect.Map:
+		var n int
+		n, err = strconv.Atoi(limit)
+		bound.compare = func(v reflect.Value) (int, string) {
+			return compare(v.Len(), n), fmt.Sprintf("length %d", v.Len())
+		}
+	default:
+		return bound, fmt.Errorf("%s tag is not supported for %v", name, t)
+	}
+	if err != nil {
+		return bound, fmt.Errorf("invalid %s tag %q: %w", name, limit, err)
+	}
+	return bound, nil
+}
+
+// validate checks a field that has just been set against the validation
+// tags compiled in fp. Nil pointers are left alone, the value is optional.
+func validate(field reflect.Value, fp *fieldPlan) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	if fp.tagErr != nil {
+		return nil // Reported once by parse
+	}
+
+	for _, bound := range fp.bounds {
+		cmp, what := bound.compare(field)
+		if bound.min && cmp < 0 {
+			return fmt.Errorf("%w: %s is less than min %s", ErrValidation, what, bound.limit)
+		}
+		if !bound.min && cmp > 0 {
+			return fmt.Errorf("%w: %s is greater than max %s", ErrValidation, what, bound.limit)
+		}
+	}
+
+	// The remaining checks apply to each element of a slice
+	elems := []reflect.Value{field}
+	if field.Kind() == reflect.Slice && !isValueType(field.Type()) {
+		if fp.nonempty && field.Len() == 0 {
+			return fmt.Errorf("%w: must not be empty", ErrValidation)
+		}
+		elems = elems[:0]
+		for i := 0; i < field.Len(); i++ {
+			elems = append(elems, field.Index(i))
+		}
+	}
+	for _, elem := range elems {
+		if err := validateValue(formatValue(elem), fp); err != nil {
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+	}
+	return nil
+}
+
+func validateValue(s string, fp *fieldPlan) error {
+	if len(fp.oneOf) > 0 && !slices.Contains(fp.oneOf, s) {
+		return fmt.Errorf("%q must be one of [%s]", s, strings.Join(fp.oneOf, ", "))
+	}
+	if fp.pattern != nil && !fp.pattern.MatchString(s) {
+		return fmt.Errorf("%q must match pattern %s", s, fp.pattern)
+	}
+	for _, check := range fp.rules {
+		if err := check(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compare[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// formatValue returns the string form of a parsed value, used by the
+// string-based checks.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,69 @@
+package envy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validatedConfig struct {
+	Port  int      `env:"V_PORT" default:"8080" min:"1" max:"65535"`
+	Level string   `env:"V_LEVEL" default:"info" oneof:"debug info warn error"`
+	Name  string   `env:"V_NAME" pattern:"^[a-z][a-z0-9-]*$"`
+	DSN   string   `env:"V_DSN" validate:"url"`
+	Addr  string   `env:"V_ADDR" validate:"hostport"`
+	Admin string   `env:"V_ADMIN" validate:"email"`
+	Hosts []string `env:"V_HOSTS" min:"1" max:"3" validate:"hostport"`
+	Ratio *float64 `env:"V_RATIO" min:"0" max:"1"`
+	Token string   `env:"V_TOKEN" validate:"nonempty"`
+}
+
+func TestLoad_Validation(t *testing.T) {
+	t.Setenv("V_NAME", "billing-api")
+	t.Setenv("V_DSN", "postgres://user:pass@db:5432/app")
+	t.Setenv("V_ADDR", "localhost:6379")
+	t.Setenv("V_ADMIN", "ops@example.com")
+	t.Setenv("V_HOSTS", "a:80,b:443")
+	t.Setenv("V_RATIO", "0.5")
+	t.Setenv("V_TOKEN", "t")
+
+	var cfg validatedConfig
+	if err := parse(&cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestLoad_ValidationFailures(t *testing.T) {
+	t.Setenv("V_PORT", "70000")
+	t.Setenv("V_LEVEL", "trace")
+	t.Setenv("V_NAME", "Billing")
+	t.Setenv("V_DSN", "not a url")
+	t.Setenv("V_ADDR", "localhost")
+	t.Setenv("V_ADMIN", "ops")
+	t.Setenv("V_HOSTS", "a:80,b:443,c:80,d:80")
+	t.Setenv("V_RATIO", "1.5")
+	t.Setenv("V_TOKEN", "")
+
+	var cfg validatedConfig
+	err := parse(&cfg)
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected *Errors, got %v", err)
+	}
+
+	keys := []string{"V_PORT", "V_LEVEL", "V_NAME", "V_DSN", "V_ADDR", "V_ADMIN", "V_HOSTS", "V_RATIO", "V_TOKEN"}
+	if len(errs.Errs) != len(keys) {
+		t.Fatalf("expected %d errors, got %d: %v", len(keys), len(errs.Errs), err)
+	}
+	for i, key := range keys {
+		if errs.Errs[i].Key != key {
+			t.Errorf("error %d: expected key %s, got %s", i, key, errs.Errs[i].Key)
+		}
+		if !errors.Is(errs.Errs[i], ErrValidation) {
+			t.Errorf("error %d: expected ErrValidation, got %v", i, errs.Errs[i])
+		}
+	}
+	if !strings.Contains(errs.Errs[0].Error(), "70000 is greater than max 65535") {
+		t.Errorf("unexpected message %q", errs.Errs[0].Error())
+	}
+}
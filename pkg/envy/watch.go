@@ -0,0 +1,173 @@
+package envy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	Path     string        // The .env file to watch, ".env" by default
+	Interval time.Duration // How often the file is polled, 1s by default
+	OnError  func(error)   // Called when a reload fails, the old config is kept
+	Options  []Option      // Passed to every load
+}
+
+// Watcher holds a config that is reloaded whenever its .env file changes.
+type Watcher[T any] struct {
+	opts    WatchOptions
+	current atomic.Pointer[T]
+	sum     []byte // Checksum of the file content last loaded
+
+	mu        sync.Mutex
+	callbacks []func(old, new *T)
+	err       error
+}
+
+// Watch loads cfg from the environment and the .env file at opts.Path, then
+// polls the file until ctx is done. On every change the config is parsed
+// into a fresh T and, if it loads and validates, atomically swapped in and
+// passed to the OnChange callbacks. A failed reload keeps the old config.
+// A file missing at first is loaded as an empty one, a file going missing
+// later keeps the current config until it's back, as when an editor or a
+// ConfigMap update replaces it.
+//
+// File values are read without being exported to the process environment,
+// real env vars always win over them. Sources set in opts.Options are
+// consulted before both. cfg only receives the initial load,
+// use Current to read the latest config.
+func Watch[T any](ctx context.Context, cfg *T, opts WatchOptions) (*Watcher[T], error) {
+	if opts.Path == "" {
+		opts.Path = ".env"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	w := &Watcher[T]{opts: opts}
+	data, err := os.ReadFile(opts.Path)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !missing {
+		return nil, err
+	}
+	if err := w.load(cfg, data); err != nil {
+		return nil, err
+	}
+	if !missing {
+		w.sum = checksum(data)
+	}
+	w.current.Store(cfg)
+
+	go w.poll(ctx)
+	return w, nil
+}
+
+// Current returns the latest successfully loaded config. It must be
+// treated as read-only, reloads swap in a new value instead of mutating it.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// OnChange registers a callback run after every successful reload.
+func (w *Watcher[T]) OnChange(fn func(old, new *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+}
+
+// Err returns the error of the last reload, nil once a reload succeeds or
+// the file is back to the content loaded last.
+func (w *Watcher[T]) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *Watcher[T]) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the config when the file content changed since the last
+// load, comparing checksums so touching the file doesn't trigger a reload.
+// A missing file is waited for, the current config is kept.
+func (w *Watcher[T]) check() {
+	data, err := os.ReadFile(w.opts.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	sum := checksum(data)
+	if bytes.Equal(sum, w.sum) {
+		// Back to the content loaded last, after a failed reload
+		w.mu.Lock()
+		w.err = nil
+		w.mu.Unlock()
+		return
+	}
+
+	next := new(T)
+	if err := w.load(next, data); err != nil {
+		w.fail(err)
+		return
+	}
+	w.sum = sum
+
+	old := w.current.Swap(next)
+
+	w.mu.Lock()
+	w.err = nil
+	callbacks := append([]func(old, new *T){}, w.callbacks...)
+	w.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(old, next)
+	}
+}
+
+func (w *Watcher[T]) fail(err error) {
+	w.mu.Lock()
+	unchanged := w.err != nil && w.err.Error() == err.Error()
+	w.err = err
+	w.mu.Unlock()
+
+	// Report a broken file once, not on every poll
+	if w.opts.OnError != nil && !unchanged {
+		w.opts.OnError(err)
+	}
+}
+
+// load parses target from the environment layered over data, the content
+// of the file, nil when it's missing.
+func (w *Watcher[T]) load(target *T, data []byte) error {
+	file, err := newDotenvSource(w.opts.Path, data, newOptions(w.opts.Options).native)
+	if err != nil {
+		return err
+	}
+	opts := append(append([]Option{}, w.opts.Options...), WithSources(EnvSource(), file))
+	return parse(target, opts...)
+}
+
+func checksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
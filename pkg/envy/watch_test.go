@@ -0,0 +1,136 @@
+package envy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchedConfig struct {
+	Port  int    `env:"W_PORT" default:"8080" max:"65535"`
+	Level string `env:"W_LEVEL" default:"info"`
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("W_PORT=9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("W_LEVEL", "debug")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	var cfg watchedConfig
+	w, err := Watch(ctx, &cfg, WatchOptions{
+		Path:     path,
+		Interval: 10 * time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Port != 9090 || w.Current().Port != 9090 {
+		t.Fatalf("expected initial port 9090, got %d", cfg.Port)
+	}
+	if _, ok := os.LookupEnv("W_PORT"); ok {
+		t.Error("expected file values not to be exported to the environment")
+	}
+
+	changes := make(chan [2]*watchedConfig, 1)
+	w.OnChange(func(old, new *watchedConfig) { changes <- [2]*watchedConfig{old, new} })
+
+	// A valid change is swapped in, process env still wins
+	if err := os.WriteFile(path, []byte("W_PORT=7070\nW_LEVEL=warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-changes:
+		if change[0].Port != 9090 || change[1].Port != 7070 || change[1].Level != "debug" {
+			t.Errorf("unexpected change %+v -> %+v", change[0], change[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	if w.Current().Port != 7070 {
+		t.Errorf("expected current port 7070, got %d", w.Current().Port)
+	}
+
+	// An invalid change keeps the old config and reports the error
+	if err := os.WriteFile(path, []byte("W_PORT=99999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected reload error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload error")
+	}
+	if w.Current().Port != 7070 || w.Err() == nil {
+		t.Errorf("expected old config to be kept, got port %d, err %v", w.Current().Port, w.Err())
+	}
+
+	// Restoring the loaded content clears the error without a reload
+	if err := os.WriteFile(path, []byte("W_PORT=7070\nW_LEVEL=warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the error to clear", func() bool { return w.Err() == nil })
+
+	// A removed file keeps the config until it's back
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-changes:
+		t.Errorf("expected no reload for a missing file, got %+v", change[1])
+	case <-time.After(100 * time.Millisecond):
+	}
+	if w.Current().Port != 7070 || w.Err() != nil {
+		t.Errorf("expected the config to be kept, got port %d, err %v", w.Current().Port, w.Err())
+	}
+	if err := os.WriteFile(path, []byte("W_PORT=6060\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-changes:
+		if change[0].Port != 7070 || change[1].Port != 6060 {
+			t.Errorf("unexpected change %+v -> %+v", change[0], change[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestWatch_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A file missing at first loads without its values, then is picked up
+	var cfg watchedConfig
+	w, err := Watch(ctx, &cfg, WatchOptions{Path: path, Interval: 10 * time.Millisecond})
+	if err != nil || cfg.Port != 8080 {
+		t.Fatalf("expected the default port and no error, got %d, %v", cfg.Port, err)
+	}
+	if err := os.WriteFile(path, []byte("W_PORT=9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the file to be loaded", func() bool { return w.Current().Port == 9090 })
+}
+
+// waitFor polls cond until it holds, failing the test after 2 seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}